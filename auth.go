@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+package registry
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// AuthHeader is the header (or gRPC metadata key) that carries the token.
+	AuthHeader = "authorization"
+
+	// AuthScheme is the scheme of the token in the AuthHeader.
+	AuthScheme = "Bearer"
+)
+
+// Authenticator authenticates the requests of the discovery api.
+type Authenticator interface {
+
+	// Authenticate returns nil if the token is valid.
+	// The errors other than Err are logged and answered as ErrTokenInvalid.
+	Authenticate(token string) error
+}
+
+// TokenAuthenticator accepts a static list of bearer tokens.
+type TokenAuthenticator struct {
+	tokens [][]byte
+}
+
+// NewTokenAuthenticator creates a TokenAuthenticator that accepts any of the tokens.
+func NewTokenAuthenticator(tokens ...string) *TokenAuthenticator {
+	a := &TokenAuthenticator{tokens: make([][]byte, 0, len(tokens))}
+	for _, token := range tokens {
+		if len(token) > 0 {
+			a.tokens = append(a.tokens, []byte(token))
+		}
+	}
+	return a
+}
+
+// Authenticate returns nil if the token is one of the accepted tokens.
+func (a *TokenAuthenticator) Authenticate(token string) error {
+	if len(token) == 0 {
+		return ErrTokenEmpty
+	}
+
+	// Compare with every token in constant time to avoid leaking which one matched.
+	matched := 0
+	for _, t := range a.tokens {
+		matched |= subtle.ConstantTimeCompare(t, []byte(token))
+	}
+	if matched == 0 {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
+// HmacAuthenticator accepts tokens signed with a shared secret by HMAC-SHA256.
+// A token is made of a subject and an expiry time, so that tokens can be issued without being configured on the registry.
+// The format is base64url("subject:expiry") + "." + base64url(signature).
+type HmacAuthenticator struct {
+	secret []byte
+}
+
+// NewHmacAuthenticator creates a HmacAuthenticator with the shared secret.
+func NewHmacAuthenticator(secret []byte) *HmacAuthenticator {
+	return &HmacAuthenticator{secret: secret}
+}
+
+// sign returns the signature of the payload.
+func (a *HmacAuthenticator) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Sign issues a token for the subject that expires after ttl.
+func (a *HmacAuthenticator) Sign(subject string, ttl time.Duration) string {
+	expiry := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(subject + ":" + expiry))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(a.sign(payload))
+}
+
+// Authenticate returns nil if the token is signed with the secret and not expired.
+func (a *HmacAuthenticator) Authenticate(token string) error {
+	if len(token) == 0 {
+		return ErrTokenEmpty
+	}
+
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrTokenInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, a.sign(payload)) {
+		return ErrTokenInvalid
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrTokenInvalid
+	}
+	idx := strings.LastIndex(string(claims), ":")
+	if idx < 0 {
+		return ErrTokenInvalid
+	}
+	expiry, err := strconv.ParseInt(string(claims[idx+1:]), 10, 64)
+	if err != nil {
+		return ErrTokenInvalid
+	}
+	if time.Now().Unix() > expiry {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// BearerToken extracts the token from the value of an AuthHeader, such as "Bearer xxx".
+func BearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, AuthScheme) {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// authExempt returns true if the gRPC method can be called without a token.
+// The health service is exempt so that the load balancers can check the registry.
+func authExempt(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/")
+}
+
+// authenticateContext authenticates the token in the metadata of a gRPC request.
+func authenticateContext(ctx context.Context, auth Authenticator) error {
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(AuthHeader); len(values) > 0 {
+			token = BearerToken(values[0])
+		}
+	}
+	return authenticate(auth, token)
+}
+
+// authenticate authenticates a token, the errors of the custom authenticators are logged and replaced by ErrTokenInvalid,
+// so that the clients can tell them apart by the code.
+func authenticate(auth Authenticator, token string) error {
+	err := auth.Authenticate(token)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(Err); ok {
+		return err
+	}
+	log.Printf("[WARN] authenticate err:%s\n", err.Error())
+	return ErrTokenInvalid
+}
+
+// UnaryAuthInterceptor returns a gRPC unary interceptor that authenticates the requests.
+func UnaryAuthInterceptor(auth Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !authExempt(info.FullMethod) {
+			if err := authenticateContext(ctx, auth); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor returns a gRPC stream interceptor that authenticates the requests.
+func StreamAuthInterceptor(auth Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !authExempt(info.FullMethod) {
+			if err := authenticateContext(ss.Context(), auth); err != nil {
+				return err
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// AuthMiddleware returns a gin middleware that authenticates the http requests.
+func AuthMiddleware(auth Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := BearerToken(c.GetHeader(AuthHeader))
+		if err := authenticate(auth, token); err != nil {
+			abort(c, err, ErrTokenInvalid)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/werbenhu/registry"
+)
+
+// TokenCredentials attaches a bearer token to every request to the registry server.
+// It implements credentials.PerRPCCredentials.
+type TokenCredentials struct {
+	token string
+}
+
+// NewTokenCredentials creates a TokenCredentials with a static token.
+func NewTokenCredentials(token string) *TokenCredentials {
+	return &TokenCredentials{token: token}
+}
+
+// GetRequestMetadata returns the authorization metadata of the request.
+func (t *TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		registry.AuthHeader: registry.AuthScheme + " " + t.token,
+	}, nil
+}
+
+// RequireTransportSecurity returns false so that the tokens can be used in trusted networks without TLS.
+// Enable TLS to protect the tokens in other networks.
+func (t *TokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// HmacCredentials signs a token with the shared secret of the registry's HmacAuthenticator,
+// and attaches it to every request. A new token is signed when half of the ttl of the current one has passed.
+// It implements credentials.PerRPCCredentials.
+type HmacCredentials struct {
+	sync.Mutex
+	auth    *registry.HmacAuthenticator
+	subject string
+	ttl     time.Duration
+	token   string
+	renew   time.Time
+}
+
+// NewHmacCredentials creates a HmacCredentials that signs tokens for the subject.
+func NewHmacCredentials(secret []byte, subject string, ttl time.Duration) *HmacCredentials {
+	return &HmacCredentials{
+		auth:    registry.NewHmacAuthenticator(secret),
+		subject: subject,
+		ttl:     ttl,
+	}
+}
+
+// GetRequestMetadata returns the authorization metadata of the request.
+func (h *HmacCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	now := time.Now()
+	if len(h.token) == 0 || now.After(h.renew) {
+		h.token = h.auth.Sign(h.subject, h.ttl)
+		h.renew = now.Add(h.ttl / 2)
+	}
+	return map[string]string{
+		registry.AuthHeader: registry.AuthScheme + " " + h.token,
+	}, nil
+}
+
+// RequireTransportSecurity returns false so that the tokens can be used in trusted networks without TLS.
+// Enable TLS to protect the tokens in other networks.
+func (h *HmacCredentials) RequireTransportSecurity() bool {
+	return false
+}
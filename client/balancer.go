@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+
+	"github.com/werbenhu/chash"
+	"github.com/werbenhu/registry"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// BalancerName is the name of the consistent hash balancer.
+	BalancerName = "registry_chash"
+
+	// KeyHeader is the metadata key that carries the routing key of a RPC,
+	// it is used if the key is not set by WithKey.
+	KeyHeader = "registry-key"
+)
+
+func init() {
+	balancer.Register(&balancerBuilder{})
+}
+
+// keyContext is the context key of the routing key.
+type keyContext struct{}
+
+// WithKey returns a context that routes the RPC to the service that owns the key,
+// the same service returned by Match(group, key).
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContext{}, key)
+}
+
+// KeyFromContext returns the routing key of the context,
+// set by WithKey or by the KeyHeader of the outgoing metadata.
+func KeyFromContext(ctx context.Context) (string, bool) {
+	if key, ok := ctx.Value(keyContext{}).(string); ok {
+		return key, true
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(KeyHeader); len(values) > 0 {
+			return values[0], true
+		}
+	}
+	return "", false
+}
+
+// balancerBuilder builds the consistent hash balancers.
+type balancerBuilder struct{}
+
+// Build creates a consistent hash balancer on top of the base balancer, which manages the connections.
+func (bb *balancerBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	b := &chashBalancer{
+		ids:    make(map[balancer.SubConn]string),
+		states: make(map[balancer.SubConn]connectivity.State),
+	}
+	b.Balancer = base.NewBalancerBuilder(BalancerName, b, base.Config{}).Build(&chashClientConn{ClientConn: cc, b: b}, opts)
+	return b
+}
+
+// Name returns the name of the balancer.
+func (bb *balancerBuilder) Name() string {
+	return BalancerName
+}
+
+// chashBalancer routes the RPCs by their keys to the services on a ring.
+// The base balancer only passes the ready connections to the picker, so the balancer
+// places all the resolved services on a ring and keeps the state of their connections by itself,
+// so that the keys are placed on the same ring as the registry's whether or not the services are connected.
+// The methods are called synchronously by gRPC, no lock is needed.
+type chashBalancer struct {
+	balancer.Balancer
+	ring   *registry.Ring // the resolved services, nil if there is none
+	ids    map[balancer.SubConn]string
+	states map[balancer.SubConn]connectivity.State
+}
+
+// UpdateClientConnState places the resolved services on a ring before the base balancer builds a new picker.
+// The ring is built once for each resolver update, the pickers built for the connection changes share it.
+func (b *chashBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	b.ring = nil
+	if addrs := s.ResolverState.Addresses; len(addrs) > 0 {
+		replicas, _ := addrs[0].Attributes.Value(attrReplicas).(int)
+		elements := make([]*chash.Element, 0, len(addrs))
+		for _, addr := range addrs {
+			id, _ := addr.Attributes.Value(attrId).(string)
+			elements = append(elements, &chash.Element{Key: id})
+		}
+		b.ring = registry.NewRing(replicas)
+		b.ring.UpsertMany(elements...)
+	}
+	return b.Balancer.UpdateClientConnState(s)
+}
+
+// UpdateSubConnState keeps the state of the connection before the base balancer builds a new picker.
+func (b *chashBalancer) UpdateSubConnState(sc balancer.SubConn, state balancer.SubConnState) {
+	s := state.ConnectivityState
+	switch {
+	case s == connectivity.Shutdown:
+		delete(b.ids, sc)
+		delete(b.states, sc)
+	case b.states[sc] == connectivity.TransientFailure && (s == connectivity.Connecting || s == connectivity.Idle):
+		// A failed connection stays failed until it is ready again, the same as the base balancer,
+		// otherwise the keys of a service that is down would wait for its reconnecting forever.
+	default:
+		b.states[sc] = s
+	}
+	b.Balancer.UpdateSubConnState(sc, state)
+}
+
+// Build builds a picker with the ring of the resolved services and the state of their connections.
+func (b *chashBalancer) Build(info base.PickerBuildInfo) balancer.Picker {
+	if b.ring == nil {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	p := &picker{
+		ring:     b.ring,
+		subConns: make(map[string]balancer.SubConn, len(info.ReadySCs)),
+		states:   make(map[string]connectivity.State, len(b.ids)),
+	}
+	for sc, id := range b.ids {
+		p.states[id] = b.states[sc]
+	}
+	for sc, sci := range info.ReadySCs {
+		id, _ := sci.Address.Attributes.Value(attrId).(string)
+		p.subConns[id] = sc
+	}
+	return p
+}
+
+// chashClientConn records the service id of each connection created by the base balancer.
+type chashClientConn struct {
+	balancer.ClientConn
+	b *chashBalancer
+}
+
+// NewSubConn creates a connection to the service of the address.
+func (cc *chashClientConn) NewSubConn(addrs []resolver.Address, opts balancer.NewSubConnOptions) (balancer.SubConn, error) {
+	sc, err := cc.ClientConn.NewSubConn(addrs, opts)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := addrs[0].Attributes.Value(attrId).(string)
+	cc.b.ids[sc] = id
+	cc.b.states[sc] = connectivity.Idle
+	return sc, nil
+}
+
+// picker picks the connection of the service that owns the routing key.
+type picker struct {
+	ring     *registry.Ring
+	subConns map[string]balancer.SubConn
+	states   map[string]connectivity.State
+}
+
+// Pick returns the connection of the service that owns the key of the RPC, the same service as Match returns.
+// If the owner is still connecting, the RPC waits for it. If the owner failed to connect,
+// the RPC is sent to the next ready service on the ring, the one that would own the key if the owner left.
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	key, ok := KeyFromContext(info.Ctx)
+	if !ok {
+		return balancer.PickResult{}, registry.ErrNoRoutingKey
+	}
+
+	// Walk the ring only until the first service that is ready or still connecting.
+	var sc balancer.SubConn
+	err := p.ring.Walk(key, func(element *chash.Element) bool {
+		sc = p.subConns[element.Key]
+		return sc == nil && p.states[element.Key] == connectivity.TransientFailure
+	})
+	if err != nil || sc == nil {
+		return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+	}
+	return balancer.PickResult{SubConn: sc}, nil
+}
@@ -0,0 +1,299 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/werbenhu/chash"
+	"github.com/werbenhu/registry"
+)
+
+// cache keeps a local ring of each group, so that the keys are matched in-process.
+// The rings are registry.Ring, which places the keys exactly the same way as the chash groups of the registry.
+type cache struct {
+	client *RpcClient
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	groups map[string]*groupCache
+
+	// file is the snapshot file of the last known membership of the groups, it is empty if disabled.
+	file   string
+	saveMu sync.Mutex
+	saved  *snapshot
+}
+
+// groupCache is the local copy of a group.
+type groupCache struct {
+	sync.RWMutex
+	ring     *registry.Ring
+	services map[string]*registry.Service
+	synced   time.Time // The last time the cache was known to be up to date.
+	live     bool      // Whether the group is up to date, it is false once the watch is broken.
+}
+
+// newCache creates a cache that loads the groups with the client.
+func newCache(c *RpcClient) *cache {
+	ctx, cancel := context.WithCancel(context.Background())
+	cc := &cache{
+		client: c,
+		ctx:    ctx,
+		cancel: cancel,
+		groups: make(map[string]*groupCache),
+		file:   c.opt.SnapshotFile,
+		saved:  &snapshot{Groups: make(map[string]*snapshotGroup)},
+	}
+
+	if len(cc.file) > 0 {
+		saved, err := loadSnapshot(cc.file)
+		if err != nil {
+			// A broken snapshot must not stop the client, the groups are loaded from the registry instead.
+			log.Printf("[ERROR] load snapshot %s err:%s\n", cc.file, err.Error())
+		} else {
+			cc.saved = saved
+		}
+	}
+	return cc
+}
+
+// close stops watching all the groups.
+func (c *cache) close() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// group returns the cached group, the group is loaded by Members and watched if it is not cached yet.
+func (c *cache) group(ctx context.Context, name string) (*groupCache, error) {
+	c.mu.Lock()
+	cached, ok := c.groups[name]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	g, err := c.load(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The group may have been loaded by another goroutine meanwhile.
+	if loaded, ok := c.groups[name]; ok {
+		return loaded, nil
+	}
+	c.groups[name] = g
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.client.keepWatching(c.ctx, name, func(e *registry.Event) {
+			g.apply(e)
+			c.save(name, g)
+		}, func(err error) {
+			log.Printf("[ERROR] watch group %s err:%s\n", name, err.Error())
+			g.broken()
+		})
+	}()
+	return g, nil
+}
+
+// load loads a group by Members. If the registry can't be reached, the group is restored from the snapshot file,
+// it is marked stale since the time it was saved until the group is watched again.
+func (c *cache) load(ctx context.Context, name string) (*groupCache, error) {
+	services, replicas, err := c.client.members(ctx, name)
+	if err == nil {
+		// The group is up to date until the watch of the group is broken.
+		g := &groupCache{synced: time.Now(), live: true}
+		g.reset(services, replicas)
+		c.save(name, g)
+		return g, nil
+	}
+
+	// The registry errors, such as group not found, are answers of the registry rather than an outage.
+	// A registry that hangs is an outage, the group is restored even if the deadline of the call has passed.
+	if _, ok := err.(registry.Err); ok {
+		return nil, err
+	}
+
+	c.saveMu.Lock()
+	saved, ok := c.saved.Groups[name]
+	c.saveMu.Unlock()
+	if !ok {
+		return nil, err
+	}
+
+	log.Printf("[WARN] load group %s err:%s, restored from the snapshot saved at %s\n", name, err.Error(), saved.Saved)
+	// The ejections are not restored, they have most likely ended since the snapshot was saved.
+	services = make([]*registry.Service, 0, len(saved.Services))
+	for _, service := range saved.Services {
+		restored := *service
+		restored.Ejected = false
+		services = append(services, &restored)
+	}
+	g := &groupCache{synced: saved.Saved}
+	g.reset(services, saved.Replicas)
+	return g, nil
+}
+
+// save writes the membership of the group to the snapshot file, if it is enabled.
+func (c *cache) save(name string, g *groupCache) {
+	if len(c.file) == 0 {
+		return
+	}
+
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	c.saved.Groups[name] = g.snapshot()
+	if err := c.saved.save(c.file); err != nil {
+		log.Printf("[ERROR] save snapshot %s err:%s\n", c.file, err.Error())
+	}
+}
+
+// staleness returns how long the cached group may have been out of date,
+// false if the group is not cached.
+func (c *cache) staleness(name string) (time.Duration, bool) {
+	c.mu.Lock()
+	g, ok := c.groups[name]
+	c.mu.Unlock()
+	if !ok {
+		return 0, false
+	}
+	return g.staleness(), true
+}
+
+// match returns the service that owns the key.
+func (c *cache) match(ctx context.Context, group string, key string) (*registry.Service, error) {
+	g, err := c.group(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	g.RLock()
+	defer g.RUnlock()
+	return g.owner(key)
+}
+
+// matchN returns up to n distinct services for the key.
+func (c *cache) matchN(ctx context.Context, group string, key string, n int) ([]*registry.Service, error) {
+	g, err := c.group(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	g.RLock()
+	defer g.RUnlock()
+	elements, err := g.ring.MatchN(key, n)
+	if err != nil {
+		return nil, registry.ToErr(err)
+	}
+	services := make([]*registry.Service, 0, len(elements))
+	for _, element := range elements {
+		services = append(services, g.services[element.Key])
+	}
+	return services, nil
+}
+
+// owner returns the service that owns the key, the caller must hold the lock.
+// The ejected services are skipped the same way as the registry does, the key is assigned to the next service
+// on the ring that is not ejected, or to its owner if all the services are ejected.
+func (g *groupCache) owner(key string) (*registry.Service, error) {
+	element, err := g.ring.Owner(key, func(id string) bool {
+		return g.services[id].Ejected
+	})
+	if err != nil {
+		return nil, registry.ToErr(err)
+	}
+	return g.services[element.Key], nil
+}
+
+// reset replaces all the services of the group and rebuilds the ring, the caller must hold the lock.
+func (g *groupCache) reset(services []*registry.Service, replicas int) {
+	g.ring = registry.NewRing(replicas)
+	g.services = make(map[string]*registry.Service, len(services))
+	g.upsert(services...)
+}
+
+// upsert inserts or updates the services, the caller must hold the lock.
+// The services are placed on the ring at once, so that the ring is sorted only once.
+func (g *groupCache) upsert(services ...*registry.Service) {
+	elements := make([]*chash.Element, 0, len(services))
+	for _, service := range services {
+		g.services[service.Id] = service
+		elements = append(elements, &chash.Element{Key: service.Id})
+	}
+	g.ring.UpsertMany(elements...)
+}
+
+// apply applies a membership event to the group.
+func (g *groupCache) apply(e *registry.Event) {
+	g.Lock()
+	defer g.Unlock()
+
+	switch e.Type {
+	case registry.EventSnapshot:
+		g.reset(e.Services, e.Replicas)
+		g.live = true
+		g.synced = time.Now()
+	case registry.EventJoin, registry.EventUpdate:
+		// The group is created with the replicas of its first service.
+		if g.ring.Len() == 0 && e.Replicas > 0 && e.Replicas != g.ring.Replicas() {
+			g.ring = registry.NewRing(e.Replicas)
+		}
+		g.upsert(e.Services...)
+	case registry.EventLeave:
+		for _, service := range e.Services {
+			delete(g.services, service.Id)
+			g.ring.Delete(service.Id)
+		}
+	}
+}
+
+// broken marks the group out of date since now, until the next snapshot is received.
+func (g *groupCache) broken() {
+	g.Lock()
+	defer g.Unlock()
+	if g.live {
+		g.live = false
+		g.synced = time.Now()
+	}
+}
+
+// snapshot returns the membership of the group to save.
+func (g *groupCache) snapshot() *snapshotGroup {
+	g.RLock()
+	defer g.RUnlock()
+
+	saved := &snapshotGroup{
+		Replicas: g.ring.Replicas(),
+		Services: make([]*registry.Service, 0, len(g.services)),
+		Saved:    g.synced,
+	}
+	if g.live {
+		saved.Saved = time.Now()
+	}
+	// The ejections are temporary, they are not saved.
+	for _, service := range g.services {
+		copied := *service
+		copied.Ejected = false
+		saved.Services = append(saved.Services, &copied)
+	}
+	return saved
+}
+
+// staleness returns 0 if the group is being watched, otherwise the time since it was last up to date.
+func (g *groupCache) staleness() time.Duration {
+	g.RLock()
+	defer g.RUnlock()
+	if g.live {
+		return 0
+	}
+	return time.Since(g.synced)
+}
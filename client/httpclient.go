@@ -0,0 +1,416 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/werbenhu/chash"
+	"github.com/werbenhu/registry"
+)
+
+// HttpError is an error response of the http api that is not one of the registry errors.
+type HttpError struct {
+	// Status is the http status code of the response.
+	Status int
+
+	// Code is the code of the response, it is 0 if the response is not a json envelope.
+	Code int
+
+	// Msg is the message of the response.
+	Msg string
+}
+
+// Error returns the error message.
+func (e *HttpError) Error() string {
+	return fmt.Sprintf("http status %d, code %d: %s", e.Status, e.Code, e.Msg)
+}
+
+// envelope is the json body of the responses of the http api.
+type envelope struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// err converts a response with a non-zero code to an error.
+// The registry error codes are returned as registry.Err, so that they can be checked with errors.Is
+// the same as the errors of RpcClient. The legacy codes of the older registry servers are mapped by their messages.
+func (e *envelope) err(status int) error {
+	if e.Code >= registry.ErrMemberIdEmpty.Code {
+		return registry.Err{Code: e.Code, Msg: e.Msg}
+	}
+	switch e.Msg {
+	case chash.ErrGroupNotFound.Error():
+		return registry.ErrGroupNotFound
+	case chash.ErrNoResultMatched.Error():
+		return registry.ErrGroupEmpty
+	case registry.ErrMatchCount.Msg:
+		return registry.ErrMatchCount
+	case registry.ErrServiceNotFound.Msg:
+		return registry.ErrServiceNotFound
+	}
+	return &HttpError{Status: status, Code: e.Code, Msg: e.Msg}
+}
+
+// HttpClient is a http client for service discovery, it calls the http api of the registry servers.
+// It can be used where gRPC is not available, such as through http proxies.
+// The groups are watched over the SSE stream of the http api. The local cache of RpcClient is not provided,
+// so neither are WatchKeys and Staleness, which are built on it.
+type HttpClient struct {
+	// Addr is the registry server addresses, separated by commas.
+	Addr string
+
+	// opt is the options of the client.
+	opt *Option
+
+	// urls is the base urls of the registry servers.
+	urls []string
+
+	// client sends the requests.
+	client *http.Client
+
+	mu sync.Mutex
+	// current is the index of the server the requests are sent to.
+	current int
+}
+
+var _ Interface = (*HttpClient)(nil)
+
+// NewHttpClient creates a new HttpClient object for the registry servers at `addr`.
+// `addr` is a comma separated list of the http addresses of the registry servers, with or without a scheme,
+// the requests move to the next server if a server can't be reached.
+// The TLS, credentials, timeout and user agent options are supported, the gRPC specific ones are ignored.
+func NewHttpClient(addr string, opts ...IOption) (*HttpClient, error) {
+	option := DefaultOption()
+	for _, o := range opts {
+		o(option)
+	}
+
+	addrs := splitAddrs(addr)
+	if len(addrs) == 0 {
+		return nil, registry.ErrRegistryAddrEmpty
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	scheme := "http://"
+	if option.TLS {
+		cfg, err := registry.NewClientTLSConfig(option.CAFile, option.CertFile, option.KeyFile, option.ServerName)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = cfg
+		scheme = "https://"
+	}
+
+	client := &HttpClient{
+		Addr:   addr,
+		opt:    option,
+		urls:   make([]string, 0, len(addrs)),
+		client: &http.Client{Transport: transport},
+	}
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "://") {
+			addr = scheme + addr
+		}
+		client.urls = append(client.urls, strings.TrimSuffix(addr, "/"))
+	}
+	return client, nil
+}
+
+// Close closes the idle connections.
+func (c *HttpClient) Close() {
+	c.client.CloseIdleConnections()
+}
+
+// timeoutContext returns a context with the timeout of the client, for the calls without a context.
+func (c *HttpClient) timeoutContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.opt.Timeout)
+}
+
+// get requests the path of the http api, and decodes the data of the response into data.
+// The request is sent to the next server if the current one can't be reached.
+func (c *HttpClient) get(ctx context.Context, path string, query url.Values, data any) error {
+	return c.request(ctx, http.MethodGet, path, query, data)
+}
+
+// post is the same as get with the POST method.
+func (c *HttpClient) post(ctx context.Context, path string, query url.Values, data any) error {
+	return c.request(ctx, http.MethodPost, path, query, data)
+}
+
+// request sends a request to the path of the http api, and decodes the data of the response into data.
+func (c *HttpClient) request(ctx context.Context, method string, path string, query url.Values, data any) error {
+	resp, err := c.send(ctx, method, path, query)
+	if err != nil {
+		return err
+	}
+	return c.decode(resp, data)
+}
+
+// send sends a request to the path of the http api, and returns the response of the first server that answers.
+// The request is sent to the next server if the current one can't be reached or fails with a server error,
+// except for a group without services, which is the same on all the servers. The POST requests, the failure
+// reports, are not idempotent and are sent to the current server only.
+func (c *HttpClient) send(ctx context.Context, method string, path string, query url.Values) (*http.Response, error) {
+	c.mu.Lock()
+	current := c.current
+	c.mu.Unlock()
+
+	attempts := len(c.urls)
+	if method == http.MethodPost {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		idx := (current + i) % len(c.urls)
+		var resp *http.Response
+		resp, err = c.do(ctx, method, c.urls[idx]+path+"?"+query.Encode())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			// The body is read to tell the error, decode closes it.
+			if err = c.decode(resp, nil); !errors.Is(err, registry.ErrGroupEmpty) {
+				continue
+			}
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.current = idx
+		c.mu.Unlock()
+		return resp, nil
+	}
+	return nil, err
+}
+
+// do sends a request with the credentials and the user agent of the client.
+func (c *HttpClient) do(ctx context.Context, method string, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.opt.Credentials != nil {
+		headers, err := c.opt.Credentials.GetRequestMetadata(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	}
+	if len(c.opt.UserAgent) > 0 {
+		req.Header.Set("User-Agent", c.opt.UserAgent)
+	}
+	return c.client.Do(req)
+}
+
+// decode decodes the envelope of the response, and its data into data.
+func (c *HttpClient) decode(resp *http.Response, data any) error {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	e := &envelope{}
+	if err := json.Unmarshal(body, e); err != nil {
+		return &HttpError{Status: resp.StatusCode, Msg: strings.TrimSpace(string(body))}
+	}
+	if e.Code != 0 {
+		return e.err(resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &HttpError{Status: resp.StatusCode, Msg: e.Msg}
+	}
+	return json.Unmarshal(e.Data, data)
+}
+
+// Match assigns a service to a key using the consistent hashing algorithm, see RpcClient.Match.
+func (c *HttpClient) Match(group string, key string) (*registry.Service, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.MatchContext(ctx, group, key)
+}
+
+// MatchContext is the same as Match with a context.
+func (c *HttpClient) MatchContext(ctx context.Context, group string, key string) (*registry.Service, error) {
+	data := &struct {
+		Service *registry.Service `json:"service"`
+	}{}
+	err := c.get(ctx, "/match", url.Values{"group": {group}, "key": {key}}, data)
+	if err != nil {
+		return nil, err
+	}
+	return data.Service, nil
+}
+
+// MatchN returns up to n distinct services for a key, see RpcClient.MatchN.
+func (c *HttpClient) MatchN(group string, key string, n int) ([]*registry.Service, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.MatchNContext(ctx, group, key, n)
+}
+
+// MatchNContext is the same as MatchN with a context.
+func (c *HttpClient) MatchNContext(ctx context.Context, group string, key string, n int) ([]*registry.Service, error) {
+	data := &struct {
+		Services []*registry.Service `json:"services"`
+	}{}
+	err := c.get(ctx, "/matchn", url.Values{"group": {group}, "key": {key}, "n": {strconv.Itoa(n)}}, data)
+	if err != nil {
+		return nil, err
+	}
+	return data.Services, nil
+}
+
+// MatchMany assigns services to a batch of keys in one round trip, see RpcClient.MatchMany.
+func (c *HttpClient) MatchMany(group string, keys []string) (*Matches, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.MatchManyContext(ctx, group, keys)
+}
+
+// MatchManyContext is the same as MatchMany with a context.
+func (c *HttpClient) MatchManyContext(ctx context.Context, group string, keys []string) (*Matches, error) {
+	data := &struct {
+		Services []*struct {
+			Service *registry.Service `json:"service"`
+			Keys    []string          `json:"keys"`
+		} `json:"services"`
+	}{}
+	err := c.get(ctx, "/matchmany", url.Values{"group": {group}, "key": keys}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := &Matches{
+		Owners: make(map[string]*registry.Service, len(keys)),
+		Shards: make([]*Shard, 0, len(data.Services)),
+	}
+	for _, shard := range data.Services {
+		for _, key := range shard.Keys {
+			matches.Owners[key] = shard.Service
+		}
+		matches.Shards = append(matches.Shards, &Shard{
+			Service: shard.Service,
+			Keys:    shard.Keys,
+		})
+	}
+	return matches, nil
+}
+
+// Members returns the list of services in a group, see RpcClient.Members.
+func (c *HttpClient) Members(group string) ([]*registry.Service, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.MembersContext(ctx, group)
+}
+
+// MembersContext is the same as Members with a context.
+func (c *HttpClient) MembersContext(ctx context.Context, group string) ([]*registry.Service, error) {
+	data := &struct {
+		Services []*registry.Service `json:"services"`
+	}{}
+	if err := c.get(ctx, "/members", url.Values{"group": {group}}, data); err != nil {
+		return make([]*registry.Service, 0), err
+	}
+	return data.Services, nil
+}
+
+// ListGroups returns the summaries of all the groups in the registry, see RpcClient.ListGroups.
+func (c *HttpClient) ListGroups() ([]*registry.GroupInfo, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.ListGroupsContext(ctx)
+}
+
+// ListGroupsContext is the same as ListGroups with a context.
+func (c *HttpClient) ListGroupsContext(ctx context.Context) ([]*registry.GroupInfo, error) {
+	data := &struct {
+		Groups []*registry.GroupInfo `json:"groups"`
+	}{}
+	if err := c.get(ctx, "/groups", url.Values{}, data); err != nil {
+		return nil, err
+	}
+	return data.Groups, nil
+}
+
+// ReportFailure reports a failed call against a service, see RpcClient.ReportFailure.
+func (c *HttpClient) ReportFailure(group string, id string) (bool, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.ReportFailureContext(ctx, group, id)
+}
+
+// ReportFailureContext is the same as ReportFailure with a context.
+func (c *HttpClient) ReportFailureContext(ctx context.Context, group string, id string) (bool, error) {
+	data := &struct {
+		Ejected bool `json:"ejected"`
+	}{}
+	if err := c.post(ctx, "/report", url.Values{"group": {group}, "id": {id}}, data); err != nil {
+		return false, err
+	}
+	return data.Ejected, nil
+}
+
+// Registries returns the registry servers of the cluster, see RpcClient.Registries.
+func (c *HttpClient) Registries() ([]*registry.Service, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.RegistriesContext(ctx)
+}
+
+// RegistriesContext is the same as Registries with a context.
+func (c *HttpClient) RegistriesContext(ctx context.Context) ([]*registry.Service, error) {
+	data := &struct {
+		Registries []*registry.Service `json:"registries"`
+	}{}
+	if err := c.get(ctx, "/registries", url.Values{}, data); err != nil {
+		return nil, err
+	}
+	return data.Registries, nil
+}
+
+// Watch watches the membership changes of a group over the SSE stream of the http api, see RpcClient.Watch.
+// The stream is opened on the first server that answers, the watcher is closed when the stream is broken.
+func (c *HttpClient) Watch(group string) (*Watcher, error) {
+	return c.WatchContext(context.Background(), group)
+}
+
+// WatchContext is the same as Watch, the watcher is stopped when the context is done.
+func (c *HttpClient) WatchContext(ctx context.Context, group string) (*Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	resp, err := c.send(ctx, http.MethodGet, "/watch", url.Values{"group": {group}})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		cancel()
+		return nil, c.decode(resp, nil)
+	}
+
+	w := &Watcher{
+		group:  group,
+		events: make(chan *registry.Event),
+		cancel: cancel,
+	}
+	go w.recvSSE(ctx, resp.Body)
+	return w, nil
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+
+	"github.com/werbenhu/registry"
+)
+
+// Interface is the service discovery api of the registry clients,
+// so that the applications can depend on it rather than a concrete client, and replace it in tests.
+type Interface interface {
+
+	// Match assigns a service to a key using the consistent hashing algorithm.
+	Match(group string, key string) (*registry.Service, error)
+
+	// MatchContext is the same as Match with a context.
+	MatchContext(ctx context.Context, group string, key string) (*registry.Service, error)
+
+	// MatchN returns up to n distinct services for a key, ordered by their distance to the key on the ring.
+	MatchN(group string, key string, n int) ([]*registry.Service, error)
+
+	// MatchNContext is the same as MatchN with a context.
+	MatchNContext(ctx context.Context, group string, key string, n int) ([]*registry.Service, error)
+
+	// MatchMany assigns services to a batch of keys.
+	MatchMany(group string, keys []string) (*Matches, error)
+
+	// MatchManyContext is the same as MatchMany with a context.
+	MatchManyContext(ctx context.Context, group string, keys []string) (*Matches, error)
+
+	// Members returns the list of services in a group.
+	Members(group string) ([]*registry.Service, error)
+
+	// MembersContext is the same as Members with a context.
+	MembersContext(ctx context.Context, group string) ([]*registry.Service, error)
+
+	// ListGroups returns the summaries of all the groups in the registry.
+	ListGroups() ([]*registry.GroupInfo, error)
+
+	// ListGroupsContext is the same as ListGroups with a context.
+	ListGroupsContext(ctx context.Context) ([]*registry.GroupInfo, error)
+
+	// ReportFailure reports a failed call against a service, it returns true if the service is ejected.
+	ReportFailure(group string, id string) (bool, error)
+
+	// ReportFailureContext is the same as ReportFailure with a context.
+	ReportFailureContext(ctx context.Context, group string, id string) (bool, error)
+
+	// Close releases the resources of the client.
+	Close()
+}
+
+var _ Interface = (*RpcClient)(nil)
+
+// New creates a gRPC client that connects to the registry servers at `addr`, see NewRpcClient.
+func New(addr string, opts ...IOption) (Interface, error) {
+	return NewRpcClient(addr, opts...)
+}
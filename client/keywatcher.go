@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/werbenhu/registry"
+)
+
+// KeyEvent is raised when the owner of a key changes.
+type KeyEvent struct {
+	// The group name of the services.
+	Group string
+
+	// The key whose owner changed.
+	Key string
+
+	// The previous owner of the key, nil for the first event of the key.
+	Old *registry.Service
+
+	// The new owner of the key, nil if the group has no service.
+	New *registry.Service
+}
+
+// KeyWatcher raises an event whenever the owner of one of its keys changes.
+type KeyWatcher struct {
+	group  string
+	keys   []string
+	events chan *KeyEvent
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	cache  *groupCache
+	owners map[string]*registry.Service
+}
+
+// Events returns the channel of the owner changes.
+// The first events carry the initial owners of the keys, with a nil Old.
+// The channel is closed when the watcher is stopped.
+func (w *KeyWatcher) Events() <-chan *KeyEvent {
+	return w.events
+}
+
+// Stop stops watching the keys.
+func (w *KeyWatcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// apply applies a membership event to the local ring of the group, and raises the events of the keys
+// whose owner changed. The keys are matched on the local ring, no call is made to the registry.
+func (w *KeyWatcher) apply(ctx context.Context, e *registry.Event) {
+	w.cache.apply(e)
+
+	w.cache.RLock()
+	changes := make([]*KeyEvent, 0)
+	for _, key := range w.keys {
+		owner, _ := w.cache.owner(key)
+
+		old := w.owners[key]
+		w.owners[key] = owner
+		if sameOwner(old, owner) {
+			// A key without owner in the first snapshot raises its first event when a service joins.
+			continue
+		}
+		changes = append(changes, &KeyEvent{Group: w.group, Key: key, Old: old, New: owner})
+	}
+	w.cache.RUnlock()
+
+	for _, change := range changes {
+		select {
+		case w.events <- change:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sameOwner returns true if both are the same service at the same address, or both are nil.
+func sameOwner(a *registry.Service, b *registry.Service) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Id == b.Id && a.Addr == b.Addr
+}
+
+// WatchKeys watches the owners of the keys in a group.
+// It watches the membership changes of the group and matches the keys on a local ring,
+// which places the keys exactly the same as the registry, so Match is not called for the keys.
+//
+// Parameters:
+// - group: The group name of the services.
+// - keys: The keys to watch, such as the keys of a cache.
+//
+// Returns:
+// - The watcher, an event is received from it whenever the owner of a key changes.
+func (c *RpcClient) WatchKeys(group string, keys []string) *KeyWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &KeyWatcher{
+		group:  group,
+		keys:   make([]string, 0, len(keys)),
+		events: make(chan *KeyEvent),
+		cancel: cancel,
+		cache:  &groupCache{},
+		owners: make(map[string]*registry.Service, len(keys)),
+	}
+	w.cache.reset(nil, 0)
+
+	// Duplicated keys are watched only once.
+	found := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			found[key] = struct{}{}
+			w.keys = append(w.keys, key)
+		}
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(w.events)
+		c.keepWatching(ctx, group, func(e *registry.Event) {
+			w.apply(ctx, e)
+		}, func(err error) {
+			log.Printf("[ERROR] watch keys of group %s err:%s\n", group, err.Error())
+		})
+	}()
+	return w
+}
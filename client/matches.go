@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import "github.com/werbenhu/registry"
+
+// Shard is a service with the keys assigned to it.
+type Shard struct {
+	// The service that owns the keys.
+	Service *registry.Service
+
+	// The keys assigned to the service.
+	Keys []string
+}
+
+// Matches is the result of matching a batch of keys.
+type Matches struct {
+	// Owners maps each key to the service it is assigned to.
+	Owners map[string]*registry.Service
+
+	// Shards groups the keys by the service they are assigned to,
+	// so that one request can be sent to each service.
+	Shards []*Shard
+}
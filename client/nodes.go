@@ -0,0 +1,298 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/werbenhu/registry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// node is a registry server the client connects to.
+type node struct {
+	addr    string
+	conn    *grpc.ClientConn
+	reg     registry.RClient
+	health  grpc_health_v1.HealthClient
+	healthy bool
+}
+
+// nodes is the set of registry servers of the client.
+// The calls are sent to the current node, and move to the next healthy node when it is unavailable.
+type nodes struct {
+	sync.Mutex
+	list    []*node
+	current int
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	// seeds is the configured addresses, they are kept when the registry servers are discovered.
+	seeds    map[string]struct{}
+	dialOpts []grpc.DialOption
+}
+
+// splitAddrs splits a comma separated list of addresses, the empty ones are ignored.
+func splitAddrs(addrs string) []string {
+	list := make([]string, 0)
+	for _, addr := range strings.Split(addrs, ",") {
+		if addr = strings.TrimSpace(addr); len(addr) > 0 {
+			list = append(list, addr)
+		}
+	}
+	return list
+}
+
+// dialNode connects to a registry server, the connection is established lazily by gRPC.
+func dialNode(addr string, dialOpts []grpc.DialOption) (*node, error) {
+	conn, err := grpc.Dial(addr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &node{
+		addr:    addr,
+		conn:    conn,
+		reg:     registry.NewRClient(conn),
+		health:  grpc_health_v1.NewHealthClient(conn),
+		healthy: true,
+	}, nil
+}
+
+// dialNodes connects to all the registry servers.
+func dialNodes(addrs []string, dialOpts []grpc.DialOption) (*nodes, error) {
+	ns := &nodes{
+		list:     make([]*node, 0, len(addrs)),
+		seeds:    make(map[string]struct{}, len(addrs)),
+		dialOpts: dialOpts,
+	}
+	for _, addr := range addrs {
+		n, err := dialNode(addr, dialOpts)
+		if err != nil {
+			ns.close()
+			return nil, err
+		}
+		ns.list = append(ns.list, n)
+		ns.seeds[addr] = struct{}{}
+	}
+	return ns, nil
+}
+
+// close stops probing and closes all the connections.
+func (ns *nodes) close() {
+	if ns.cancel != nil {
+		ns.cancel()
+		ns.wg.Wait()
+	}
+
+	ns.Lock()
+	defer ns.Unlock()
+	for _, n := range ns.list {
+		n.conn.Close()
+	}
+}
+
+// all returns a copy of the list of the nodes.
+func (ns *nodes) all() []*node {
+	ns.Lock()
+	defer ns.Unlock()
+	return append([]*node(nil), ns.list...)
+}
+
+// sync connects to the discovered registry servers that are not known yet,
+// and disconnects from the discovered ones that are gone. The seeds are always kept.
+func (ns *nodes) sync(addrs []string) {
+	ns.Lock()
+	defer ns.Unlock()
+
+	found := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		found[addr] = struct{}{}
+	}
+
+	current := ns.list[ns.current]
+	known := make(map[string]struct{}, len(ns.list))
+	list := make([]*node, 0, len(ns.list))
+	for _, n := range ns.list {
+		_, seed := ns.seeds[n.addr]
+		if _, ok := found[n.addr]; !ok && !seed {
+			log.Printf("[INFO] registry server %s is gone\n", n.addr)
+			n.conn.Close()
+			continue
+		}
+		known[n.addr] = struct{}{}
+		list = append(list, n)
+	}
+
+	for _, addr := range addrs {
+		if _, ok := known[addr]; ok {
+			continue
+		}
+		n, err := dialNode(addr, ns.dialOpts)
+		if err != nil {
+			log.Printf("[ERROR] dial registry server %s err:%s\n", addr, err.Error())
+			continue
+		}
+		log.Printf("[INFO] registry server %s is discovered\n", addr)
+		known[addr] = struct{}{}
+		list = append(list, n)
+	}
+
+	ns.list = list
+	ns.current = 0
+	for i, n := range list {
+		if n == current {
+			ns.current = i
+		}
+	}
+}
+
+// order returns the nodes to try a call on: the current node and the other healthy nodes first,
+// then the unhealthy ones as the last resort.
+func (ns *nodes) order() []*node {
+	ns.Lock()
+	defer ns.Unlock()
+
+	healthy := make([]*node, 0, len(ns.list))
+	unhealthy := make([]*node, 0)
+	for i := range ns.list {
+		n := ns.list[(ns.current+i)%len(ns.list)]
+		if n.healthy {
+			healthy = append(healthy, n)
+		} else {
+			unhealthy = append(unhealthy, n)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// mark records the health of a node, a healthy node that served a call becomes the current node.
+func (ns *nodes) mark(n *node, healthy bool) {
+	ns.Lock()
+	defer ns.Unlock()
+
+	n.healthy = healthy
+	if healthy && !ns.list[ns.current].healthy {
+		for i, item := range ns.list {
+			if item == n {
+				ns.current = i
+			}
+		}
+	}
+}
+
+// failover returns true if an attempt failed because the node can't be reached, or didn't answer
+// within the time of the attempt while the call still has time, so that the call can be sent to another node safely.
+func failover(ctx context.Context, err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return true
+	case codes.DeadlineExceeded:
+		return ctx.Err() == nil
+	}
+	return false
+}
+
+// attemptContext returns the context of an attempt, n is the number of the nodes left to try.
+// If ctx has a deadline, the time left is split evenly among the nodes left, so that a node that hangs
+// doesn't use up the time of the others. The last node gets all the time left.
+func attemptContext(ctx context.Context, n int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || n <= 1 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(n))
+}
+
+// call invokes fn on the nodes in order, until it succeeds or fails with an error that is not worth failing over.
+// Each attempt has its own context, see attemptContext. It must only be used by the idempotent calls.
+func (ns *nodes) call(ctx context.Context, fn func(ctx context.Context, reg registry.RClient) error) error {
+	var err error
+	list := ns.order()
+	for i, n := range list {
+		attemptCtx, cancel := attemptContext(ctx, len(list)-i)
+		err = fn(attemptCtx, n.reg)
+		cancel()
+		if err == nil || !failover(ctx, err) {
+			ns.mark(n, true)
+			return err
+		}
+		ns.mark(n, false)
+	}
+	return err
+}
+
+// callOnce invokes fn on the first node in order only, for the calls that are not idempotent,
+// such as the failure reports, which would be counted again by another node.
+func (ns *nodes) callOnce(ctx context.Context, fn func(ctx context.Context, reg registry.RClient) error) error {
+	n := ns.order()[0]
+	err := fn(ctx, n.reg)
+	ns.mark(n, err == nil || !failover(ctx, err))
+	return err
+}
+
+// probe checks the health of all the nodes every interval, until the nodes are closed.
+// A node is healthy if its health service reports serving, or if the health service is disabled
+// on the registry server but the node can be reached.
+// If discover is not nil, the nodes are synced with the registry servers it returns before each check.
+func (ns *nodes) probe(interval time.Duration, discover func(ctx context.Context) ([]string, error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ns.cancel = cancel
+
+	ns.wg.Add(1)
+	go func() {
+		defer ns.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if discover != nil {
+				discoverCtx, discoverCancel := context.WithTimeout(ctx, interval)
+				addrs, err := discover(discoverCtx)
+				discoverCancel()
+				if ctx.Err() != nil {
+					return
+				}
+				// A registry server always knows itself once it serves, no server means a broken answer.
+				if err == nil && len(addrs) > 0 {
+					ns.sync(addrs)
+				}
+			}
+
+			for _, n := range ns.all() {
+				ns.Lock()
+				healthy := n.healthy
+				ns.Unlock()
+
+				// The connection to a failed node backs off for up to minutes before reconnecting,
+				// reconnect it now and wait for it, so that it is used again as soon as it is back.
+				callOpts := make([]grpc.CallOption, 0)
+				if !healthy {
+					n.conn.ResetConnectBackoff()
+					callOpts = append(callOpts, grpc.WaitForReady(true))
+				}
+
+				checkCtx, checkCancel := context.WithTimeout(ctx, interval)
+				resp, err := n.health.Check(checkCtx, &grpc_health_v1.HealthCheckRequest{}, callOpts...)
+				checkCancel()
+				if ctx.Err() != nil {
+					return
+				}
+				ns.mark(n, status.Code(err) == codes.Unimplemented ||
+					(err == nil && resp.Status == grpc_health_v1.HealthCheckResponse_SERVING))
+			}
+		}
+	}()
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Option represents the options for the registry client.
+type Option struct {
+
+	// TLS enables TLS to connect to the registry server.
+	TLS bool
+
+	// CAFile is the PEM encoded CA used to verify the registry server.
+	// If it is empty, the system CAs are used. It is reloaded when the file is modified.
+	CAFile string
+
+	// ServerName overrides the name used to verify the registry server's certificate.
+	ServerName string
+
+	// CertFile and KeyFile are the PEM encoded client certificate and key for mutual TLS.
+	// They are reloaded when the files are modified.
+	CertFile string
+	KeyFile  string
+
+	// Credentials attaches the credentials, such as a token, to every request.
+	Credentials credentials.PerRPCCredentials
+
+	// Cache keeps a local ring of each group, so that Match and MatchN are resolved in-process.
+	// The rings are loaded from the registry on first use and kept fresh by watching the groups.
+	Cache bool
+
+	// SnapshotFile is the file to save the last known membership of the cached groups, it enables Cache as well.
+	// If the registry can't be reached when a group is loaded, such as at startup during an outage,
+	// the ring of the group is restored from the file, and Staleness reports the time since it was saved.
+	SnapshotFile string
+
+	// ProbeInterval is the interval to check the health of the registry servers,
+	// a failed server is used again once it passes the check.
+	// The servers are probed only if the client connects to more than one server, or Discover is enabled.
+	ProbeInterval time.Duration
+
+	// Discover finds all the registry servers of the cluster from the configured ones, which can be a single seed,
+	// and tracks the servers that join and leave every ProbeInterval. The configured servers are always kept.
+	Discover bool
+
+	// Timeout is the timeout of the calls without a context, such as Match.
+	// The calls with a context, such as MatchContext, use the deadline of the context instead.
+	// The time left is split among the registry servers left to try, so that a call fails over
+	// to the next server in time when a server hangs.
+	Timeout time.Duration
+
+	// Keepalive sends pings on the idle connections to keep them alive and detect broken ones, it is disabled if nil.
+	Keepalive *keepalive.ClientParameters
+
+	// UserAgent is prepended to the gRPC user agent of the requests.
+	UserAgent string
+
+	// UnaryInterceptors and StreamInterceptors are chained to the calls, such as tracing and metrics interceptors.
+	UnaryInterceptors  []grpc.UnaryClientInterceptor
+	StreamInterceptors []grpc.StreamClientInterceptor
+
+	// DialOptions are appended to the dial options of the client, they override the ones set by the other options.
+	DialOptions []grpc.DialOption
+}
+
+// IOption represents a function that modifies the Option.
+type IOption func(o *Option)
+
+// OptTLS enables TLS with the CA file and the server name option, both of them can be empty.
+func OptTLS(caFile string, serverName string) IOption {
+	return func(o *Option) {
+		o.TLS = true
+		o.CAFile = caFile
+		o.ServerName = serverName
+	}
+}
+
+// OptClientCert sets the client certificate and key files for mutual TLS option, it enables TLS as well.
+func OptClientCert(certFile string, keyFile string) IOption {
+	return func(o *Option) {
+		o.TLS = true
+		o.CertFile = certFile
+		o.KeyFile = keyFile
+	}
+}
+
+// OptCredentials sets the credentials attached to every request option.
+func OptCredentials(creds credentials.PerRPCCredentials) IOption {
+	return func(o *Option) {
+		o.Credentials = creds
+	}
+}
+
+// OptToken attaches a static bearer token to every request option.
+func OptToken(token string) IOption {
+	return OptCredentials(NewTokenCredentials(token))
+}
+
+// OptHmacToken attaches a token signed with the shared secret to every request option.
+// The tokens are issued for the subject and expire after ttl, they are renewed automatically.
+func OptHmacToken(secret []byte, subject string, ttl time.Duration) IOption {
+	return OptCredentials(NewHmacCredentials(secret, subject, ttl))
+}
+
+// OptCache enables the local ring cache option.
+func OptCache(cache bool) IOption {
+	return func(o *Option) {
+		o.Cache = cache
+	}
+}
+
+// OptSnapshotFile sets the file to save the last known membership of the groups option, it enables the cache.
+func OptSnapshotFile(file string) IOption {
+	return func(o *Option) {
+		o.Cache = true
+		o.SnapshotFile = file
+	}
+}
+
+// OptProbeInterval sets the interval to check the health of the registry servers option.
+func OptProbeInterval(interval time.Duration) IOption {
+	return func(o *Option) {
+		o.ProbeInterval = interval
+	}
+}
+
+// OptDiscover sets whether the registry servers are discovered from the configured ones option.
+func OptDiscover(enable bool) IOption {
+	return func(o *Option) {
+		o.Discover = enable
+	}
+}
+
+// OptTimeout sets the timeout of the calls without a context option.
+func OptTimeout(timeout time.Duration) IOption {
+	return func(o *Option) {
+		o.Timeout = timeout
+	}
+}
+
+// OptKeepalive sets the keepalive parameters of the connections option.
+func OptKeepalive(params keepalive.ClientParameters) IOption {
+	return func(o *Option) {
+		o.Keepalive = &params
+	}
+}
+
+// OptUserAgent sets the user agent of the requests option.
+func OptUserAgent(userAgent string) IOption {
+	return func(o *Option) {
+		o.UserAgent = userAgent
+	}
+}
+
+// OptUnaryInterceptors appends the unary interceptors of the calls option.
+func OptUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) IOption {
+	return func(o *Option) {
+		o.UnaryInterceptors = append(o.UnaryInterceptors, interceptors...)
+	}
+}
+
+// OptStreamInterceptors appends the stream interceptors of the calls option.
+func OptStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) IOption {
+	return func(o *Option) {
+		o.StreamInterceptors = append(o.StreamInterceptors, interceptors...)
+	}
+}
+
+// OptDialOptions appends the gRPC dial options option.
+func OptDialOptions(opts ...grpc.DialOption) IOption {
+	return func(o *Option) {
+		o.DialOptions = append(o.DialOptions, opts...)
+	}
+}
+
+// DefaultOption returns the default options for the registry client.
+func DefaultOption() *Option {
+	return &Option{
+		ProbeInterval: 5 * time.Second,
+		Timeout:       5 * time.Second,
+	}
+}
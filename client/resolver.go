@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/werbenhu/registry"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+const (
+	// Scheme is the scheme of the targets resolved by the registry, such as "registry:///webservice-group".
+	Scheme = "registry"
+
+	// serviceConfig selects the consistent hash balancer for the resolved connections.
+	serviceConfig = `{"loadBalancingConfig":[{"` + BalancerName + `":{}}]}`
+)
+
+// attrKey is the type of the keys of the address attributes set by the resolver.
+type attrKey string
+
+const (
+	// attrId is the attribute of the service id of an address.
+	attrId attrKey = "id"
+
+	// attrReplicas is the attribute of the replicas of the group's ring.
+	attrReplicas attrKey = "replicas"
+)
+
+// ResolverBuilder builds the resolvers of the "registry" scheme.
+// The target "registry:///group" is resolved to the addresses of the services in the group,
+// and kept up to date by watching the group on the registry server.
+type ResolverBuilder struct {
+	client *RpcClient
+}
+
+// NewResolverBuilder creates a resolver builder that resolves the groups with the client.
+// Use it with grpc.WithResolvers, or register it globally with resolver.Register.
+//
+//	conn, err := grpc.Dial("registry:///webservice-group",
+//		grpc.WithResolvers(client.NewResolverBuilder(c)),
+//		grpc.WithTransportCredentials(insecure.NewCredentials()),
+//	)
+func NewResolverBuilder(c *RpcClient) *ResolverBuilder {
+	return &ResolverBuilder{client: c}
+}
+
+// Build creates a resolver that watches the group of the target.
+func (b *ResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &groupResolver{
+		client:   b.client,
+		group:    target.Endpoint(),
+		cc:       cc,
+		cancel:   cancel,
+		services: make(map[string]*registry.Service),
+		config:   cc.ParseServiceConfig(serviceConfig),
+	}
+	r.wg.Add(1)
+	go r.watch(ctx)
+	return r, nil
+}
+
+// Scheme returns the scheme of the registry targets.
+func (b *ResolverBuilder) Scheme() string {
+	return Scheme
+}
+
+// groupResolver resolves a group to the addresses of its services.
+type groupResolver struct {
+	client *RpcClient
+	group  string
+	cc     resolver.ClientConn
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	config *serviceconfig.ParseResult
+
+	services map[string]*registry.Service
+	replicas int
+}
+
+// ResolveNow is a no-op, the addresses are pushed whenever the group changes.
+func (r *groupResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+// Close stops watching the group.
+func (r *groupResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// watch watches the group and updates the addresses until the resolver is closed.
+// If the stream is broken, the last addresses are kept and the group is watched again.
+func (r *groupResolver) watch(ctx context.Context) {
+	defer r.wg.Done()
+	r.client.keepWatching(ctx, r.group, func(e *registry.Event) {
+		r.apply(e)
+		// The balancer rejects an empty group, the RPCs fail until a service joins.
+		r.update()
+	}, func(err error) {
+		log.Printf("[ERROR] watch group %s err:%s\n", r.group, err.Error())
+		r.cc.ReportError(err)
+	})
+}
+
+// apply applies a membership event to the services of the group.
+func (r *groupResolver) apply(e *registry.Event) {
+	if e.Replicas > 0 {
+		r.replicas = e.Replicas
+	}
+	switch e.Type {
+	case registry.EventSnapshot:
+		r.services = make(map[string]*registry.Service, len(e.Services))
+		for _, service := range e.Services {
+			r.services[service.Id] = service
+		}
+	case registry.EventJoin, registry.EventUpdate:
+		for _, service := range e.Services {
+			r.services[service.Id] = service
+		}
+	case registry.EventLeave:
+		for _, service := range e.Services {
+			delete(r.services, service.Id)
+		}
+	}
+}
+
+// update pushes the addresses of the services to the gRPC connection.
+// The service id and the replicas are set in the attributes so that the balancer
+// places the services on its ring exactly as the registry does.
+func (r *groupResolver) update() error {
+	replicas := r.replicas
+	if replicas <= 0 {
+		replicas, _ = strconv.Atoi(registry.DefaultReplicas)
+	}
+
+	addrs := make([]resolver.Address, 0, len(r.services))
+	for _, service := range r.services {
+		addrs = append(addrs, resolver.Address{
+			Addr:       service.Addr,
+			Attributes: attributes.New(attrId, service.Id).WithValue(attrReplicas, replicas),
+		})
+	}
+
+	return r.cc.UpdateState(resolver.State{
+		Addresses:     addrs,
+		ServiceConfig: r.config,
+	})
+}
@@ -7,41 +7,135 @@ package client
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/werbenhu/registry"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // RpcClient is a gRPC client for service discovery.
 type RpcClient struct {
-	// Addr is the registry server address.
+	// Addr is the registry server addresses, separated by commas.
 	Addr string
 
-	// conn is the gRPC connection.
-	conn *grpc.ClientConn
+	// opt is the options of the client.
+	opt *Option
 
-	// reg is the gRPC client object.
-	reg registry.RClient
+	// nodes is the registry servers the client connects to.
+	nodes *nodes
+
+	// cache is the local rings of the groups, it is nil if the cache is disabled.
+	cache *cache
 }
 
-// NewRpcClient creates a new RpcClient object and connects to the registry server at `addr`.
-func NewRpcClient(addr string) (*RpcClient, error) {
-	client := &RpcClient{Addr: addr}
-	// Connect to the registry server.
-	conn, err := grpc.Dial(client.Addr, grpc.WithInsecure())
+// NewRpcClient creates a new RpcClient object and connects to the registry servers at `addr`.
+// `addr` is a comma separated list of registry servers, such as "172.16.3.3:9000,172.16.3.4:9000".
+// The calls are sent to a healthy server, and Match, Members and the other queries are retried
+// on another server if the server is unavailable.
+func NewRpcClient(addr string, opts ...IOption) (*RpcClient, error) {
+	option := DefaultOption()
+	for _, o := range opts {
+		o(option)
+	}
+
+	client := &RpcClient{Addr: addr, opt: option}
+	creds, err := client.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(append([]grpc.UnaryClientInterceptor{errorInterceptor}, option.UnaryInterceptors...)...),
+		grpc.WithChainStreamInterceptor(option.StreamInterceptors...),
+	}
+	if option.Credentials != nil {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(option.Credentials))
+	}
+	if option.Keepalive != nil {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(*option.Keepalive))
+	}
+	if len(option.UserAgent) > 0 {
+		dialOpts = append(dialOpts, grpc.WithUserAgent(option.UserAgent))
+	}
+	dialOpts = append(dialOpts, option.DialOptions...)
+
+	// Connect to the registry servers.
+	addrs := splitAddrs(addr)
+	if len(addrs) == 0 {
+		return nil, registry.ErrRegistryAddrEmpty
+	}
+	client.nodes, err = dialNodes(addrs, dialOpts)
 	if err != nil {
 		return nil, err
 	}
+	// The other registry servers are found from the seeds, and tracked while probing the servers.
+	var discover func(ctx context.Context) ([]string, error)
+	if option.Discover {
+		discover = client.registryAddrs
+		ctx, cancel := client.timeoutContext()
+		if addrs, err := discover(ctx); err != nil {
+			log.Printf("[WARN] discover registry servers from %s err:%s\n", addr, err.Error())
+		} else if len(addrs) > 0 {
+			client.nodes.sync(addrs)
+		}
+		cancel()
+	}
 
-	client.conn = conn
-	client.reg = registry.NewRClient(conn)
+	// A single server has nowhere to fail over, so it is not probed unless more servers can be discovered.
+	if len(addrs) > 1 || option.Discover {
+		client.nodes.probe(option.ProbeInterval, discover)
+	}
+
+	if option.Cache {
+		client.cache = newCache(client)
+	}
 	return client, nil
 }
 
+// errorInterceptor converts the status errors that carry a registry error code back to registry.Err,
+// so that they can be checked with errors.Is, such as errors.Is(err, registry.ErrGroupNotFound).
+func errorInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return registry.FromStatusError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// credentials returns the transport credentials to connect to the registry server.
+func (c *RpcClient) credentials() (credentials.TransportCredentials, error) {
+	if !c.opt.TLS {
+		return insecure.NewCredentials(), nil
+	}
+
+	cfg, err := registry.NewClientTLSConfig(c.opt.CAFile, c.opt.CertFile, c.opt.KeyFile, c.opt.ServerName)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// timeoutContext returns a context with the timeout of the client, for the calls without a context.
+func (c *RpcClient) timeoutContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.opt.Timeout)
+}
+
+// newService converts the gRPC response object to a service.
+func newService(resp *registry.MatchResponse) *registry.Service {
+	service := registry.NewService(resp.Id, resp.Group, resp.Addr)
+	if len(resp.Meta) > 0 {
+		service.Meta = resp.Meta
+	}
+	service.Ejected = resp.Ejected
+	return service
+}
+
 // Close closes the gRPC client connection.
 func (c *RpcClient) Close() {
-	c.conn.Close()
+	if c.cache != nil {
+		c.cache.close()
+	}
+	c.nodes.close()
 }
 
 // Match assigns a service to a key using the consistent hashing algorithm.
@@ -54,18 +148,120 @@ func (c *RpcClient) Close() {
 // - The service that matches the key.
 // - An error if the service cannot be found.
 func (c *RpcClient) Match(group string, key string) (*registry.Service, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := c.timeoutContext()
 	defer cancel()
+	return c.MatchContext(ctx, group, key)
+}
+
+// MatchContext is the same as Match with a context, which carries the deadline, cancellation and trace context of the caller.
+// The Timeout option is not applied, the call has no deadline unless the context has one.
+func (c *RpcClient) MatchContext(ctx context.Context, group string, key string) (*registry.Service, error) {
+	if c.cache != nil {
+		return c.cache.match(ctx, group, key)
+	}
+
+	var service *registry.MatchResponse
+	err := c.nodes.call(ctx, func(ctx context.Context, reg registry.RClient) (err error) {
+		service, err = reg.Match(ctx, &registry.MatchRequest{
+			Group: group,
+			Key:   key,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The service contains the service ID, group name, service address and meta.
+	return newService(service), nil
+}
+
+// MatchN returns up to n distinct services for a key, ordered by their distance to the key on the ring.
+// The first one is the service that Match returns, the next one would own the key if the first one left, and so on.
+//
+// Parameters:
+// - group: The group name of the services.
+// - key: The key, such as user ID, device ID, etc.
+// - n: The number of services, such as the number of replicas of the data.
+//
+// Returns:
+// - The services for the key, fewer than n if there are not enough services in the group.
+// - An error if the services cannot be found.
+func (c *RpcClient) MatchN(group string, key string, n int) ([]*registry.Service, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.MatchNContext(ctx, group, key, n)
+}
+
+// MatchNContext is the same as MatchN with a context.
+func (c *RpcClient) MatchNContext(ctx context.Context, group string, key string, n int) ([]*registry.Service, error) {
+	if c.cache != nil {
+		return c.cache.matchN(ctx, group, key, n)
+	}
+
+	var resp *registry.MatchNResponse
+	err := c.nodes.call(ctx, func(ctx context.Context, reg registry.RClient) (err error) {
+		resp, err = reg.MatchN(ctx, &registry.MatchNRequest{
+			Group: group,
+			Key:   key,
+			N:     int32(n),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]*registry.Service, 0, len(resp.Services))
+	for _, service := range resp.Services {
+		services = append(services, newService(service))
+	}
+	return services, nil
+}
+
+// MatchMany assigns services to a batch of keys in one round trip.
+//
+// Parameters:
+// - group: The group name of the services.
+// - keys: The keys, such as user IDs, device IDs, etc.
+//
+// Returns:
+// - The services that match the keys, both per key and grouped by service.
+// - An error if the services cannot be found.
+func (c *RpcClient) MatchMany(group string, keys []string) (*Matches, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.MatchManyContext(ctx, group, keys)
+}
 
-	service, err := c.reg.Match(ctx, &registry.MatchRequest{
-		Group: group,
-		Key:   key,
+// MatchManyContext is the same as MatchMany with a context.
+func (c *RpcClient) MatchManyContext(ctx context.Context, group string, keys []string) (*Matches, error) {
+	var resp *registry.MatchManyResponse
+	err := c.nodes.call(ctx, func(ctx context.Context, reg registry.RClient) (err error) {
+		resp, err = reg.MatchMany(ctx, &registry.MatchManyRequest{
+			Group: group,
+			Keys:  keys,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
-	// The service contains three attributes: service ID, group name, and service address.
-	return registry.NewService(service.Id, service.Group, service.Addr), nil
+
+	matches := &Matches{
+		Owners: make(map[string]*registry.Service, len(resp.Owners)),
+		Shards: make([]*Shard, 0, len(resp.Services)),
+	}
+	for _, shard := range resp.Services {
+		service := newService(shard.Service)
+		for _, key := range shard.Keys {
+			matches.Owners[key] = service
+		}
+		matches.Shards = append(matches.Shards, &Shard{
+			Service: service,
+			Keys:    shard.Keys,
+		})
+	}
+	return matches, nil
 }
 
 // Members returns the list of services in a group.
@@ -77,19 +273,197 @@ func (c *RpcClient) Match(group string, key string) (*registry.Service, error) {
 // - The list of services in the group.
 // - An error if the group does not exist or cannot be accessed.
 func (c *RpcClient) Members(group string) ([]*registry.Service, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := c.timeoutContext()
 	defer cancel()
+	return c.MembersContext(ctx, group)
+}
 
+// MembersContext is the same as Members with a context.
+func (c *RpcClient) MembersContext(ctx context.Context, group string) ([]*registry.Service, error) {
+	services, _, err := c.members(ctx, group)
+	return services, err
+}
+
+// members returns the list of services in a group and the replicas of the group's ring.
+func (c *RpcClient) members(ctx context.Context, group string) ([]*registry.Service, int, error) {
 	services := make([]*registry.Service, 0)
-	members, err := c.reg.Members(ctx, &registry.MembersRequest{
-		Group: group,
+	var members *registry.MembersResponse
+	err := c.nodes.call(ctx, func(ctx context.Context, reg registry.RClient) (err error) {
+		members, err = reg.Members(ctx, &registry.MembersRequest{
+			Group: group,
+		})
+		return err
 	})
 	if err != nil {
-		return services, err
+		return services, 0, err
 	}
 
 	for _, member := range members.Services {
-		services = append(services, registry.NewService(member.Id, member.Group, member.Addr))
+		services = append(services, newService(member))
+	}
+	return services, int(members.Replicas), nil
+}
+
+// ReportFailure reports a failed call against a service returned by Match, such as a connection error.
+// The registry ejects the service once it fails too often, its keys are assigned to the next service
+// on the ring until the ejection ends, and the service is marked Ejected in Members.
+//
+// Parameters:
+// - group: The group name of the service.
+// - id: The id of the service.
+//
+// Returns:
+// - True if the service is ejected.
+// - An error if the service is not found or the registry cannot be accessed.
+func (c *RpcClient) ReportFailure(group string, id string) (bool, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.ReportFailureContext(ctx, group, id)
+}
+
+// ReportFailureContext is the same as ReportFailure with a context.
+func (c *RpcClient) ReportFailureContext(ctx context.Context, group string, id string) (bool, error) {
+	// The report is sent to one registry server only, a report that timed out may have been counted.
+	var resp *registry.ReportFailureResponse
+	err := c.nodes.callOnce(ctx, func(ctx context.Context, reg registry.RClient) (err error) {
+		resp, err = reg.ReportFailure(ctx, &registry.ReportFailureRequest{
+			Group: group,
+			Id:    id,
+		})
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.Ejected, nil
+}
+
+// Registries returns the registry servers of the cluster.
+//
+// Returns:
+// - The registry servers, the Addr of each one is the address it advertises to the clients.
+// - An error if the registry cannot be accessed.
+func (c *RpcClient) Registries() ([]*registry.Service, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.RegistriesContext(ctx)
+}
+
+// RegistriesContext is the same as Registries with a context.
+func (c *RpcClient) RegistriesContext(ctx context.Context) ([]*registry.Service, error) {
+	var resp *registry.RegistriesResponse
+	err := c.nodes.call(ctx, func(ctx context.Context, reg registry.RClient) (err error) {
+		resp, err = reg.Registries(ctx, &registry.RegistriesRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]*registry.Service, 0, len(resp.Registries))
+	for _, service := range resp.Registries {
+		services = append(services, newService(service))
 	}
 	return services, nil
 }
+
+// registryAddrs returns the advertised addresses of the registry servers.
+func (c *RpcClient) registryAddrs(ctx context.Context) ([]string, error) {
+	services, err := c.RegistriesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(services))
+	for _, service := range services {
+		if len(service.Addr) > 0 {
+			addrs = append(addrs, service.Addr)
+		}
+	}
+	return addrs, nil
+}
+
+// Watch watches the membership changes of a group.
+//
+// Parameters:
+// - group: The group name of the services.
+//
+// Returns:
+// - The watcher, the first event received from it is a snapshot of the group.
+// - An error if the watch stream cannot be opened.
+func (c *RpcClient) Watch(group string) (*Watcher, error) {
+	return c.WatchContext(context.Background(), group)
+}
+
+// WatchContext is the same as Watch, the watcher is stopped when the context is done.
+func (c *RpcClient) WatchContext(ctx context.Context, group string) (*Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	var stream registry.R_WatchClient
+	// The stream outlives the attempt, so it is opened with the context of the watcher.
+	err := c.nodes.call(ctx, func(_ context.Context, reg registry.RClient) (err error) {
+		stream, err = reg.Watch(ctx, &registry.WatchRequest{
+			Group: group,
+		})
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, registry.FromStatusError(err)
+	}
+
+	w := &Watcher{
+		group:  group,
+		events: make(chan *registry.Event),
+		cancel: cancel,
+	}
+	go w.recv(ctx, stream)
+	return w, nil
+}
+
+// ListGroups returns the summaries of all the groups in the registry.
+//
+// Returns:
+// - The summaries of the groups, sorted by name.
+// - An error if the groups cannot be listed.
+func (c *RpcClient) ListGroups() ([]*registry.GroupInfo, error) {
+	ctx, cancel := c.timeoutContext()
+	defer cancel()
+	return c.ListGroupsContext(ctx)
+}
+
+// ListGroupsContext is the same as ListGroups with a context.
+func (c *RpcClient) ListGroupsContext(ctx context.Context) ([]*registry.GroupInfo, error) {
+	var resp *registry.ListGroupsResponse
+	err := c.nodes.call(ctx, func(ctx context.Context, reg registry.RClient) (err error) {
+		resp, err = reg.ListGroups(ctx, &registry.ListGroupsRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*registry.GroupInfo, 0, len(resp.Groups))
+	for _, group := range resp.Groups {
+		groups = append(groups, &registry.GroupInfo{
+			Name:     group.Name,
+			Members:  int(group.Members),
+			Replicas: int(group.Replicas),
+			Updated:  time.UnixMilli(group.Updated),
+		})
+	}
+	return groups, nil
+}
+
+// Staleness returns how long the cached ring of a group may have been out of date.
+// It is 0 while the group is being watched, and grows from the moment the watch is broken
+// until the registry is reachable again, when Match is still answered by the last known ring.
+// A ring restored from the snapshot file is stale since the time it was saved.
+//
+// Returns:
+// - The staleness of the group.
+// - False if the cache is disabled or the group is not cached yet.
+func (c *RpcClient) Staleness(group string) (time.Duration, bool) {
+	if c.cache == nil {
+		return 0, false
+	}
+	return c.cache.staleness(group)
+}
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/werbenhu/registry"
+)
+
+// snapshotGroup is the last known membership of a group saved in the snapshot file.
+type snapshotGroup struct {
+	// The number of replicated elements of each service on the group's ring.
+	Replicas int `json:"replicas"`
+
+	// The services of the group.
+	Services []*registry.Service `json:"services"`
+
+	// The time the membership was last known to be up to date.
+	Saved time.Time `json:"saved"`
+}
+
+// snapshot is the content of the snapshot file.
+type snapshot struct {
+	Groups map[string]*snapshotGroup `json:"groups"`
+}
+
+// loadSnapshot reads the snapshot file, an empty snapshot is returned if the file doesn't exist.
+func loadSnapshot(file string) (*snapshot, error) {
+	s := &snapshot{Groups: make(map[string]*snapshotGroup)}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Groups == nil {
+		s.Groups = make(map[string]*snapshotGroup)
+	}
+	return s, nil
+}
+
+// save writes the snapshot to a temporary file and renames it to the file,
+// so that the file is never left half written if the process crashes.
+func (s *snapshot) save(file string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/werbenhu/registry"
+)
+
+// KeyFunc returns the routing key of a request, false if the request has no key.
+type KeyFunc func(req *http.Request) (string, bool)
+
+// KeyFromHeader returns the routing key from a request header.
+func KeyFromHeader(name string) KeyFunc {
+	return func(req *http.Request) (string, bool) {
+		key := req.Header.Get(name)
+		return key, len(key) > 0
+	}
+}
+
+// KeyFromQuery returns the routing key from a query parameter of the request url.
+func KeyFromQuery(name string) KeyFunc {
+	return func(req *http.Request) (string, bool) {
+		key := req.URL.Query().Get(name)
+		return key, len(key) > 0
+	}
+}
+
+// KeyFromPath returns the routing key from a segment of the request path, the first segment is 0.
+// For example, the key of "/users/42/profile" is "42" with the index 1.
+func KeyFromPath(index int) KeyFunc {
+	return func(req *http.Request) (string, bool) {
+		segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+		if index < 0 || index >= len(segments) || len(segments[index]) == 0 {
+			return "", false
+		}
+		return segments[index], true
+	}
+}
+
+// Transport is a http.RoundTripper that routes the requests by key to the services of a group.
+// The request is sent to the Addr of the service that owns its key, the same service returned by Match,
+// the scheme and the path of the request are kept. If the service can't be connected,
+// the request is retried on the next service on the ring.
+//
+//	c := &http.Client{Transport: client.NewTransport(rc, "user-service", client.KeyFromHeader("X-User-Id"))}
+//	resp, err := c.Get("http://user-service/users/profile")
+type Transport struct {
+	// Client resolves the services of the keys, a client with the cache enabled resolves them in-process.
+	Client Interface
+
+	// Group is the group name of the services.
+	Group string
+
+	// Key returns the routing key of a request. If it is nil, the key is set by WithKey on the request context.
+	Key KeyFunc
+
+	// Attempts is the number of services a request is sent to at most, one after another on connection errors.
+	// The requests with a body are retried only if the body can be got again, see http.Request.GetBody.
+	Attempts int
+
+	// Report reports the services that can't be connected to the registry, see Interface.ReportFailure.
+	Report bool
+
+	// Base sends the rewritten requests, http.DefaultTransport is used if it is nil.
+	Base http.RoundTripper
+}
+
+// NewTransport creates a Transport that routes the requests to the services of the group,
+// it tries up to 2 services and reports the services that can't be connected.
+func NewTransport(c Interface, group string, key KeyFunc) *Transport {
+	return &Transport{
+		Client:   c,
+		Group:    group,
+		Key:      key,
+		Attempts: 2,
+		Report:   true,
+	}
+}
+
+// base returns the RoundTripper that sends the rewritten requests.
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// key returns the routing key of the request.
+func (t *Transport) key(req *http.Request) (string, bool) {
+	if t.Key == nil {
+		return KeyFromContext(req.Context())
+	}
+	return t.Key(req)
+}
+
+// RoundTrip sends the request to the service that owns its key.
+// Only the connection errors are retried, the request has not been sent to the service in that case,
+// any other error or response of the service is returned as it is.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, ok := t.key(req)
+	if !ok {
+		closeBody(req)
+		return nil, registry.ErrNoRoutingKey
+	}
+
+	attempts := t.Attempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	// The first service is the one Match returns, the ejected services are skipped.
+	service, err := t.Client.MatchContext(req.Context(), t.Group, key)
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	services := []*registry.Service{service}
+	for i := 0; i < len(services); i++ {
+		service := services[i]
+		r := req.Clone(req.Context())
+		r.URL.Host = service.Addr
+		r.Host = service.Addr
+		if i > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, err
+			}
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp *http.Response
+		resp, err = t.base().RoundTrip(r)
+		if err == nil || !isDialError(err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		if t.Report {
+			if _, reportErr := t.Client.ReportFailureContext(req.Context(), t.Group, service.Id); reportErr != nil {
+				log.Printf("[WARN] report failure of %s err:%s\n", service.Id, reportErr.Error())
+			}
+		}
+
+		// The services to retry on are resolved only once the first one can't be connected.
+		if i == 0 && attempts > 1 {
+			successors, matchErr := t.Client.MatchNContext(req.Context(), t.Group, key, attempts)
+			if matchErr != nil {
+				return nil, err
+			}
+			services = append(services, retries(successors, service.Id, attempts-1)...)
+		}
+	}
+	return nil, err
+}
+
+// retries returns up to n services to retry on other than the first one, the ejected services are tried last,
+// the same order Match skips them in.
+func retries(services []*registry.Service, first string, n int) []*registry.Service {
+	list := make([]*registry.Service, 0, len(services))
+	for _, service := range services {
+		if service.Id != first {
+			list = append(list, service)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return !list[i].Ejected && list[j].Ejected
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// isDialError returns true if the connection to the service can't be established,
+// so that the request can be sent to another service safely.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// closeBody closes the body of a request that is not sent, as a RoundTripper must do.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/werbenhu/registry"
+)
+
+// retryInterval is the interval to watch a group again after the stream is broken.
+const retryInterval = time.Second
+
+// Watcher receives the membership changes of a group from the registry server.
+type Watcher struct {
+	group  string
+	events chan *registry.Event
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+// Events returns the channel of the membership changes.
+// The first event is always a snapshot of all the services in the group,
+// followed by join, leave and update events.
+// The channel is closed when the watcher is stopped or the stream is broken, see Err().
+func (w *Watcher) Events() <-chan *registry.Event {
+	return w.events
+}
+
+// Err returns the error that broke the stream, it is nil if the watcher is still running or stopped by Stop().
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Stop stops watching the group.
+func (w *Watcher) Stop() {
+	w.cancel()
+}
+
+// recv reads the stream until it is broken and forwards the events to the channel.
+func (w *Watcher) recv(ctx context.Context, stream registry.R_WatchClient) {
+	defer close(w.events)
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			// An error caused by Stop() is not reported.
+			if ctx.Err() == nil {
+				w.mu.Lock()
+				w.err = registry.FromStatusError(err)
+				w.mu.Unlock()
+			}
+			return
+		}
+
+		e := &registry.Event{
+			Type:     resp.Type,
+			Group:    w.group,
+			Services: make([]*registry.Service, 0, len(resp.Services)),
+			Replicas: int(resp.Replicas),
+		}
+		for _, service := range resp.Services {
+			e.Services = append(e.Services, newService(service))
+		}
+
+		select {
+		case w.events <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// recvSSE reads the SSE stream of the http api until it is broken and forwards the events to the channel.
+// The type of each event is in its data, the ids, the event names and the comments such as the heartbeats are skipped.
+func (w *Watcher) recvSSE(ctx context.Context, body io.ReadCloser) {
+	defer close(w.events)
+	defer body.Close()
+
+	reader := bufio.NewReader(body)
+	data := make([]byte, 0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// An error caused by Stop() is not reported, the stream closed by the server is reported as ErrWatcherClosed.
+			if ctx.Err() == nil {
+				if err == io.EOF {
+					err = registry.ErrWatcherClosed
+				}
+				w.mu.Lock()
+				w.err = err
+				w.mu.Unlock()
+			}
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "data:") {
+			// The data lines of an event are joined by newlines.
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+			continue
+		}
+		// A blank line ends an event.
+		if len(line) > 0 || len(data) == 0 {
+			continue
+		}
+
+		e := &registry.Event{}
+		err = json.Unmarshal(data, e)
+		data = data[:0]
+		if err != nil {
+			w.mu.Lock()
+			w.err = err
+			w.mu.Unlock()
+			return
+		}
+
+		select {
+		case w.events <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// keepWatching watches the group until the context is done, the events are passed to apply.
+// Whenever the stream is broken, broken is called and the group is watched again after retryInterval,
+// the first event of the new stream is a snapshot that brings the watcher up to date.
+func (c *RpcClient) keepWatching(ctx context.Context, group string, apply func(*registry.Event), broken func(error)) {
+	for {
+		w, err := c.WatchContext(ctx, group)
+		if err == nil {
+			err = w.forward(ctx, apply)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		broken(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// forward passes the events to apply until the context is done or the stream is broken.
+func (w *Watcher) forward(ctx context.Context, apply func(*registry.Event)) error {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-w.Events():
+			if !ok {
+				if err := w.Err(); err != nil {
+					return err
+				}
+				return registry.ErrWatcherClosed
+			}
+			apply(e)
+		}
+	}
+}
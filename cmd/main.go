@@ -8,7 +8,9 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/werbenhu/registry"
 )
@@ -20,11 +22,39 @@ func main() {
 	registries := flag.String("registries", "", "Registry server addresses, it can be empty, and multiples are separated by commas.")
 	addr := flag.String("addr", ":9800", "The address used for service discovery (default \":9800\").")
 	advertise := flag.String("advertise", "", "The address will advertise to client for service discover (default \":9800\").")
+	rpc := flag.Bool("rpc", true, "Enable the gRPC api for service discovery on -addr (default true).")
+	httpAddr := flag.String("http-addr", "", "The address of the http api for service discovery, the http api is enabled if it is set.")
+	httpAdvertise := flag.String("http-advertise", "", "The address of the http api will advertise to client (default -http-addr).")
+	health := flag.Bool("health", true, "Enable the gRPC health service (default true).")
+	reflection := flag.Bool("reflection", true, "Enable the gRPC server reflection (default true).")
+	certFile := flag.String("cert-file", "", "The certificate file of the discovery api, serve over TLS if it is set.")
+	keyFile := flag.String("key-file", "", "The key file of the discovery api.")
+	authTokens := flag.String("auth-tokens", "", "The bearer tokens accepted by the discovery api, multiples are separated by commas.")
+	authHmacSecret := flag.String("auth-hmac-secret", "", "The secret to verify the HMAC signed tokens of the discovery api.")
+	clientCAFile := flag.String("client-ca-file", "", "The CA file to verify the client certificates, require mutual TLS if it is set.")
+	ejectThreshold := flag.Int("eject-threshold", 0, "The number of failures reported by the clients that ejects a service, 0 disables the ejection (default 0).")
+	ejectWindow := flag.Duration("eject-window", 10*time.Second, "The window of the failures that eject a service (default 10s).")
+	ejectCooldown := flag.Duration("eject-cooldown", 30*time.Second, "How long a service stays ejected (default 30s).")
+	ejectMaxPercent := flag.Int("eject-max-percent", 50, "The maximum percentage of the services of a group that are ejected at the same time (default 50).")
+	registerGrace := flag.Duration("register-grace", 10*time.Second, "How long a service registered by a gRPC stream stays registered after the stream breaks (default 10s).")
 
 	flag.Parse()
 	if *id == "" {
 		log.Fatal(registry.ErrMemberIdEmpty)
 	}
+	if !*rpc && *httpAddr == "" {
+		log.Fatal(registry.ErrNoApiEnabled)
+	}
+
+	// Requests are authenticated with static tokens or HMAC signed tokens.
+	var auth registry.Authenticator
+	if *authTokens != "" && *authHmacSecret != "" {
+		log.Fatal(registry.ErrAuthConflict)
+	} else if *authTokens != "" {
+		auth = registry.NewTokenAuthenticator(strings.Split(*authTokens, ",")...)
+	} else if *authHmacSecret != "" {
+		auth = registry.NewHmacAuthenticator([]byte(*authHmacSecret))
+	}
 
 	sigs := make(chan os.Signal, 1)
 	done := make(chan bool, 1)
@@ -40,7 +70,17 @@ func main() {
 		registry.OptBindAdvertise(*bindAdvertise),
 		registry.OptAddr(*addr),
 		registry.OptAdvertise(*advertise),
+		registry.OptRpc(*rpc),
+		registry.OptHttp(*httpAddr, *httpAdvertise),
 		registry.OptRegistries(*registries),
+		registry.OptHealth(*health),
+		registry.OptReflection(*reflection),
+		registry.OptTLS(*certFile, *keyFile),
+		registry.OptClientCA(*clientCAFile),
+		registry.OptAuthenticator(auth),
+		registry.OptEjection(*ejectThreshold, *ejectWindow, *ejectCooldown),
+		registry.OptEjectMaxPercent(*ejectMaxPercent),
+		registry.OptRegisterGrace(*registerGrace),
 	})
 
 	go r.Serve()
@@ -17,6 +17,14 @@ type Handler interface {
 	OnMemberUpdate(*Member) error
 }
 
+// UserEventHandler is implemented by a Handler that receives the custom events broadcast by the members.
+type UserEventHandler interface {
+
+	// OnUserEvent is triggered when a custom event is received, including the events broadcast by the local member.
+	// ltime is the Lamport time of the event in the cluster.
+	OnUserEvent(name string, ltime uint64, payload []byte) error
+}
+
 // Auto-discover interface.
 type Discovery interface {
 
@@ -29,6 +37,12 @@ type Discovery interface {
 	// LocalMember returns the current service.
 	LocalMember() *Member
 
+	// UserEvent broadcasts a custom event to all the members.
+	UserEvent(name string, payload []byte) error
+
+	// EventTime returns the Lamport time of the custom events.
+	EventTime() uint64
+
 	// Start starts the discovery service.
 	Start() error
 
@@ -4,6 +4,16 @@
 
 package registry
 
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/werbenhu/chash"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
 // Err represents a custom error type with an error message and error code.
 type Err struct {
 	Msg  string // the error message
@@ -27,4 +37,133 @@ var (
 	ErrGroupNameEmpty      = Err{Code: 10001, Msg: "member group name empty"}
 	ErrParseAddrToHostPort = Err{Code: 10002, Msg: "parse addr to host and port error"}
 	ErrParsePort           = Err{Code: 10003, Msg: "parse port error"}
+	ErrWatcherClosed       = Err{Code: 10004, Msg: "watcher closed, too slow to receive events"}
+	ErrMatchCount          = Err{Code: 10005, Msg: "the number of services to match must be greater than 0"}
+	ErrParseCA             = Err{Code: 10006, Msg: "no CA certificate found in the pem file"}
+	ErrNoPeerCertificate   = Err{Code: 10007, Msg: "no certificate presented by the peer"}
+	ErrTokenEmpty          = Err{Code: 10008, Msg: "token can't be empty"}
+	ErrTokenInvalid        = Err{Code: 10009, Msg: "token is invalid"}
+	ErrTokenExpired        = Err{Code: 10010, Msg: "token is expired"}
+	ErrAuthConflict        = Err{Code: 10011, Msg: "only one of static tokens and hmac secret can be set"}
+	ErrGroupNotFound       = Err{Code: 10012, Msg: "group not found"}
+	ErrGroupEmpty          = Err{Code: 10013, Msg: "no service in the group"}
+	ErrNoRoutingKey        = Err{Code: 10014, Msg: "no routing key in the context or the request"}
+	ErrRegistryAddrEmpty   = Err{Code: 10015, Msg: "registry server address can't be empty"}
+	ErrServiceNotFound     = Err{Code: 10016, Msg: "service not found"}
+	ErrNoApiEnabled        = Err{Code: 10018, Msg: "neither the gRPC api nor the http api is enabled"}
+	ErrServiceAddrEmpty    = Err{Code: 10019, Msg: "service address can't be empty"}
+	ErrServiceConflict     = Err{Code: 10020, Msg: "service id is used by a service registered over serf"}
+	ErrRequestBody         = Err{Code: 10021, Msg: "invalid request body"}
+	ErrInternal            = Err{Code: 10022, Msg: "internal error"}
 )
+
+// grpcCodes maps the pre-defined errors to the gRPC status codes.
+var grpcCodes = map[Err]codes.Code{
+	ErrMemberIdEmpty:       codes.InvalidArgument,
+	ErrReplicasParam:       codes.InvalidArgument,
+	ErrGroupNameEmpty:      codes.InvalidArgument,
+	ErrParseAddrToHostPort: codes.InvalidArgument,
+	ErrParsePort:           codes.InvalidArgument,
+	ErrWatcherClosed:       codes.Aborted,
+	ErrMatchCount:          codes.InvalidArgument,
+	ErrParseCA:             codes.Internal,
+	ErrNoPeerCertificate:   codes.Unauthenticated,
+	ErrTokenEmpty:          codes.Unauthenticated,
+	ErrTokenInvalid:        codes.Unauthenticated,
+	ErrTokenExpired:        codes.Unauthenticated,
+	ErrAuthConflict:        codes.InvalidArgument,
+	ErrGroupNotFound:       codes.NotFound,
+	ErrGroupEmpty:          codes.FailedPrecondition,
+	ErrNoRoutingKey:        codes.Internal, // A balancer picker is not allowed to fail a RPC with InvalidArgument.
+	ErrRegistryAddrEmpty:   codes.InvalidArgument,
+	ErrServiceNotFound:     codes.NotFound,
+	ErrNoApiEnabled:        codes.InvalidArgument,
+	ErrServiceAddrEmpty:    codes.InvalidArgument,
+	ErrServiceConflict:     codes.AlreadyExists,
+	ErrRequestBody:         codes.InvalidArgument,
+	ErrInternal:            codes.Internal,
+}
+
+// GRPCStatus returns the gRPC status of the error, the error code is carried in the status details.
+// It makes the gRPC server return the proper status code when a handler returns an Err.
+func (e Err) GRPCStatus() *status.Status {
+	code, ok := grpcCodes[e]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	st := status.New(code, e.Msg)
+	if detailed, err := st.WithDetails(&ErrorDetail{Code: int32(e.Code), Msg: e.Msg}); err == nil {
+		return detailed
+	}
+	return st
+}
+
+// HTTPStatus returns the http status of the error, it is derived from the gRPC status code of the error,
+// so that both apis classify the errors the same way. A group without services is 503 Service Unavailable.
+func (e Err) HTTPStatus() int {
+	switch grpcCodes[e] {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// ToErr converts the errors of chash to the pre-defined errors, other errors are returned as they are.
+func ToErr(err error) error {
+	switch {
+	case errors.Is(err, chash.ErrGroupNotFound):
+		return ErrGroupNotFound
+	case errors.Is(err, chash.ErrNoResultMatched):
+		return ErrGroupEmpty
+	}
+	return err
+}
+
+// ToStatusError converts an error to a gRPC status error.
+// The pre-defined errors and the chash errors are mapped to their status codes, context errors to
+// Canceled and DeadlineExceeded, and the other errors that are not status errors to Internal.
+func ToStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	err = ToErr(err)
+	if e, ok := err.(Err); ok {
+		return e.GRPCStatus().Err()
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// FromStatusError converts a gRPC status error that carries an error code back to an Err,
+// so that it can be checked with errors.Is, such as errors.Is(err, ErrGroupNotFound).
+// Other errors are returned as they are.
+func FromStatusError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return err
+	}
+
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*ErrorDetail); ok {
+			return Err{Code: int(d.Code), Msg: d.Msg}
+		}
+	}
+	return err
+}
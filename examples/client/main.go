@@ -33,6 +33,21 @@ func main() {
 		log.Printf("[INFO] Matched key: %s, Service ID: %s, Service Address: %s\n", key, service.Id, service.Addr)
 	}
 
+	// Assign services to a batch of users in one round trip.
+	keys := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("user-id-%d", i))
+	}
+	matches, err := client.MatchMany(group, keys)
+	if err != nil {
+		log.Printf("[ERROR] Failed to match keys: %s\n", err)
+	} else {
+		// Send one request per service with all the keys it owns.
+		for _, shard := range matches.Shards {
+			log.Printf("[INFO] Service ID: %s, Service Address: %s, Keys: %d\n", shard.Service.Id, shard.Service.Addr, len(shard.Keys))
+		}
+	}
+
 	// Get all services of the group.
 	allService, err := client.Members(group)
 	if err != nil {
@@ -53,6 +53,9 @@ func main() {
 	// addr: The address currently provided by this service to the client.
 	reg := register.New(*id, *bind, *advertise, *registries, *group, *addr)
 
+	// Extra information of the service, clients get it from the meta of the matched service.
+	reg.SetTag("protocol", "http")
+
 	// Start the registry.
 	err = reg.Start()
 	if err != nil {
@@ -1,22 +1,73 @@
 package registry
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/werbenhu/chash"
+	"github.com/rs/xid"
 )
 
 // Http represents the http server object
 type Http struct {
 	addr     string       // the address that http server listens to
-	listener net.Listener // the listener for the http server
+	opt      *Option      // the options of the registry
+	server   *http.Server // the http server, closing it closes the open connections as well
+	stopped  bool         // whether Stop is called, so that Start does not serve after that
+	serverMu sync.Mutex   // guards the server and stopped, Start and Stop may run on different goroutines
+	outliers *Outliers    // the services ejected for the failures reported by the clients
+	watchers *Watchers    // the subscribers of the SSE watch streams
+	leases   *Leases      // the services registered through the http api, nil if the registration is disabled
+
+	mu      sync.Mutex
+	epoch   string   // identifies the http server in the event ids, so that ids of another server are not resumed
+	seq     uint64   // the sequence number of the last event
+	history []*Event // the latest events, to resume the SSE watch streams
 }
 
-// NewHttp returns a new Http object
+// NewHttp returns a new Http object with the default options
 func NewHttp() *Http {
-	return &Http{}
+	opt := DefaultOption()
+	return newHttp(opt, NewOutliers(opt.EjectThreshold, opt.EjectWindow, opt.EjectCooldown, opt.EjectMaxPercent, nil), nil)
+}
+
+// newHttp returns a new Http object with the options, the outliers and the registrations of the registry
+func newHttp(opt *Option, outliers *Outliers, leases *Leases) *Http {
+	return &Http{
+		opt:      opt,
+		outliers: outliers,
+		leases:   leases,
+		watchers: NewWatchers(),
+		epoch:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:  make([]*Event, 0),
+	}
+}
+
+// fail returns an error response, the http status and the code are the ones of the registry error,
+// such as 404 with the code of ErrGroupNotFound. The other errors are returned with the code of ErrInternal.
+func (h *Http) fail(c *gin.Context, err error) {
+	abort(c, err, ErrInternal)
+}
+
+// abort aborts a request with an error response, the http status and the code are the ones of the registry error.
+// The other errors are returned with their own message, and the http status and the code of fallback,
+// so that every error response carries the code of a registry error.
+func abort(c *gin.Context, err error, fallback Err) {
+	status := fallback.HTTPStatus()
+	e, ok := ToErr(err).(Err)
+	if ok {
+		status = e.HTTPStatus()
+	} else {
+		e = Err{Code: fallback.Code, Msg: err.Error()}
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"code": e.Code,
+		"msg":  e.Msg,
+	})
 }
 
 // match assigns a service to a key using consistent hashing algorithm
@@ -24,36 +75,27 @@ func (h *Http) match(c *gin.Context) {
 	name := c.Query("group")
 	key := c.Query("key")
 
-	// Get the group based on the provided name
-	group, err := chash.GetGroup(name)
+	// Get the ring of the group based on the provided name
+	ring, err := getRing(name)
 	if err != nil {
-		// Return error response if group not found
-		c.JSON(http.StatusOK, gin.H{
-			"code": 1,
-			"msg":  err.Error(),
-		})
+		// Return 404 if group not found
+		h.fail(c, err)
 		return
 	}
 
-	// Match the key with a member in the group
-	_, payload, err := group.Match(key)
+	// Match the key with a member in the group, skipping the ejected services
+	_, payload, err := h.outliers.owner(name, ring, key)
 	if err != nil {
-		// Return error response if key not found
-		c.JSON(http.StatusOK, gin.H{
-			"code": 2,
-			"msg":  err.Error(),
-		})
+		// Return 503 if there is no service in the group
+		h.fail(c, err)
 		return
 	}
 
 	// Unmarshal the member payload
 	m := &Member{}
 	if err := m.Unmarshal(payload); err != nil {
-		// Return error response if payload cannot be unmarshalled
-		c.JSON(http.StatusOK, gin.H{
-			"code": 3,
-			"msg":  err.Error(),
-		})
+		// Return 500 if payload cannot be unmarshalled
+		h.fail(c, err)
 		return
 	}
 
@@ -62,7 +104,60 @@ func (h *Http) match(c *gin.Context) {
 		"code": 0,
 		"msg":  "success",
 		"data": gin.H{
-			"service": m.Service,
+			"service": h.outliers.mark(&m.Service),
+		},
+	})
+}
+
+// matchN returns up to n distinct services for a key, ordered by their distance to the key on the ring
+func (h *Http) matchN(c *gin.Context) {
+	name := c.Query("group")
+	key := c.Query("key")
+
+	// Parse the number of services to match
+	n, err := strconv.Atoi(c.DefaultQuery("n", "1"))
+	if err != nil {
+		// Return 400 if n is not a number
+		h.fail(c, ErrMatchCount)
+		return
+	}
+
+	services, err := matchN(h.outliers, name, key, n)
+	if err != nil {
+		// Return error response if no service matched
+		h.fail(c, err)
+		return
+	}
+
+	// Return success response with the matched services
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"services": services,
+		},
+	})
+}
+
+// matchMany assigns services to a batch of keys, the keys are passed by repeated key parameters
+func (h *Http) matchMany(c *gin.Context) {
+	name := c.Query("group")
+	keys := c.QueryArray("key")
+
+	owners, shards, err := matchMany(h.outliers, name, keys)
+	if err != nil {
+		// Return error response if no service matched
+		h.fail(c, err)
+		return
+	}
+
+	// Return success response with the owner of each key and the keys grouped by service
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"owners":   owners,
+			"services": shards,
 		},
 	})
 }
@@ -70,24 +165,21 @@ func (h *Http) match(c *gin.Context) {
 // members returns the list of services for a group
 func (h *Http) members(c *gin.Context) {
 	name := c.Query("group")
-	// Get the group based on the provided name
-	group, err := chash.GetGroup(name)
+	// Get the ring of the group based on the provided name
+	ring, err := getRing(name)
 	if err != nil {
-		// Return error response if group not found
-		c.JSON(http.StatusOK, gin.H{
-			"code": 1,
-			"msg":  err.Error(),
-		})
+		// Return 404 if group not found
+		h.fail(c, err)
 		return
 	}
 
 	// Get all the elements in the group and extract their services
-	elements := group.GetElements()
+	elements := ring.Elements()
 	services := make([]Service, 0)
 	for _, element := range elements {
 		m := &Member{}
 		if err := m.Unmarshal(element.Payload); err == nil {
-			services = append(services, m.Service)
+			services = append(services, *h.outliers.mark(&m.Service))
 		}
 	}
 
@@ -101,26 +193,195 @@ func (h *Http) members(c *gin.Context) {
 	})
 }
 
+// report records a failed call of a client against a service, the service is ejected once it fails too often
+func (h *Http) report(c *gin.Context) {
+	ejected, err := reportFailure(h.outliers, c.Query("group"), c.Query("id"))
+	if err != nil {
+		// Return 404 if the group or the service is not found
+		h.fail(c, err)
+		return
+	}
+
+	// Return success response with the ejection state of the service
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"ejected": ejected,
+		},
+	})
+}
+
+// registration is the body of a service registration of the http api.
+type registration struct {
+	// Id is the id of the service, a random id is generated if it is empty.
+	Id string `json:"id"`
+
+	// Group is the group name of the service.
+	Group string `json:"group"`
+
+	// Addr is the address of the service provided to the clients.
+	Addr string `json:"addr"`
+
+	// Tags are the extra information of the service, the same as the tags set by register.Register.SetTag.
+	// The tags reserved by the registry, group, addr, replicas and http, are rejected.
+	Tags map[string]string `json:"tags"`
+
+	// TTL is the number of seconds the service stays registered without a heartbeat, 30 seconds if it is 0.
+	TTL int `json:"ttl"`
+}
+
+// register registers a service that stays in its group while its heartbeats are received within its TTL
+func (h *Http) register(c *gin.Context) {
+	req := &registration{}
+	if err := c.ShouldBindJSON(req); err != nil || req.TTL < 0 || checkTags(req.Tags) != nil {
+		h.fail(c, ErrRequestBody)
+		return
+	}
+	if len(req.Id) == 0 {
+		req.Id = xid.New().String()
+	}
+	ttl := DefaultLeaseTTL
+	if req.TTL > 0 {
+		ttl = time.Duration(req.TTL) * time.Second
+	}
+
+	m := NewMember(req.Id, "", "", "", req.Group, req.Addr)
+	for k, v := range req.Tags {
+		m.SetTag(k, v)
+	}
+	if err := h.leases.Register(m, ttl); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	// Return success response with the registered service, its id is needed by the heartbeats
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"service": &m.Service,
+			"ttl":     int(ttl / time.Second),
+		},
+	})
+}
+
+// heartbeat renews the registration of a service for its TTL
+func (h *Http) heartbeat(c *gin.Context) {
+	m, ttl, err := h.leases.Renew(c.Param("id"))
+	if err != nil {
+		// The service has expired or is not registered, it should register again
+		h.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"service": &m.Service,
+			"ttl":     int(ttl / time.Second),
+		},
+	})
+}
+
+// deregister removes a registered service from its group
+func (h *Http) deregister(c *gin.Context) {
+	if err := h.leases.Deregister(c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// groups returns the summaries of all the groups
+func (h *Http) groups(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"groups": listGroups(),
+		},
+	})
+}
+
+// registries returns the registry servers of the cluster
+func (h *Http) registries(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"registries": registries(),
+		},
+	})
+}
+
 // Start starts the http server
 func (h *Http) Start(addr string) error {
 	var err error
 	h.addr = addr
 
 	r := gin.Default()
+	if h.opt.Authenticator != nil {
+		r.Use(AuthMiddleware(h.opt.Authenticator))
+	}
 	r.GET("/match", h.match)
+	r.GET("/matchn", h.matchN)
+	r.GET("/matchmany", h.matchMany)
 	r.GET("/members", h.members)
+	r.GET("/groups", h.groups)
+	r.POST("/report", h.report)
+	r.GET("/registries", h.registries)
+	r.GET("/watch", h.watch)
+
+	// The services are registered through the http api only if it is served by a registry server
+	if h.leases != nil {
+		r.PUT("/services", h.register)
+		r.PUT("/services/:id/heartbeat", h.heartbeat)
+		r.DELETE("/services/:id", h.deregister)
+	}
 
 	// Listen on the provided address and run the http server
-	h.listener, err = net.Listen("tcp", h.addr)
+	listener, err := net.Listen("tcp", h.addr)
 	if err != nil {
 		return err
 	}
-	return r.RunListener(h.listener)
+
+	// Serve https if the certificate is set
+	if len(h.opt.CertFile) > 0 {
+		cfg, err := NewServerTLSConfig(h.opt.CertFile, h.opt.KeyFile, h.opt.ClientCAFile)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, cfg)
+	}
+
+	// Stop may have been called while listening, the server is not started then.
+	h.serverMu.Lock()
+	if h.stopped {
+		h.serverMu.Unlock()
+		listener.Close()
+		return http.ErrServerClosed
+	}
+	h.server = &http.Server{Handler: r.Handler()}
+	server := h.server
+	h.serverMu.Unlock()
+	return server.Serve(listener)
 }
 
-// Stop stops the http server
+// Stop stops the http server, the server is not started if Start is called afterwards
 func (h *Http) Stop() {
-	if h.listener != nil {
-		h.listener.Close()
+	h.watchers.Close()
+	h.serverMu.Lock()
+	h.stopped = true
+	server := h.server
+	h.serverMu.Unlock()
+	if server != nil {
+		server.Close()
 	}
 }
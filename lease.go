@@ -0,0 +1,362 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLeaseTTL is the TTL of the registrations that don't set one.
+	DefaultLeaseTTL = 30 * time.Second
+
+	// registerGrace is the default grace period of the services registered by the Register streams.
+	registerGrace = 10 * time.Second
+
+	// leaseEvent is the name of the serf user events that replicate the registrations.
+	leaseEvent = "registry-lease"
+
+	leasePut    = "put"    // a service is registered or updated, or its registration is refreshed
+	leaseDelete = "delete" // a service is deregistered
+)
+
+// leaseMessage is a change of the registrations replicated to the other registry servers.
+type leaseMessage struct {
+	Origin  string        `json:"origin"`           // The id of the registry server that made the change.
+	Version uint64        `json:"version"`          // The version of the change, see Leases.clock.
+	Op      string        `json:"op"`               // The change, put or delete.
+	Id      string        `json:"id"`               // The id of the service.
+	Member  *Member       `json:"member,omitempty"` // The member of the service, set by put.
+	TTL     time.Duration `json:"ttl,omitempty"`    // The TTL of the registration.
+}
+
+// lease is a registration of a service and the timer that removes it when its TTL expires.
+// A deregistered service is kept without member until its TTL expires, so that the changes
+// made before the deregistration are ignored if they are delivered late.
+type lease struct {
+	member     *Member
+	ttl        time.Duration
+	timer      *time.Timer
+	replicated time.Time // the time the registration was last put, by this registry server or another one
+
+	// version orders the changes of a service made by the registry servers, since the serf user events
+	// may be delivered out of order. The changes of the same version are ordered by origin.
+	version uint64
+	origin  string
+}
+
+// newer returns true if a change is made after the last change of the lease.
+func (s *lease) newer(version uint64, origin string) bool {
+	return s == nil || version > s.version || (version == s.version && origin > s.origin)
+}
+
+// Leases keeps the services registered through the apis of the registry servers, for the services
+// that can't join the serf cluster. A service stays in its group while it is renewed within its TTL.
+// The registrations, updates and deregistrations are replicated to the other registry servers by serf user events,
+// the renewals are not. Each registry server expires the registrations by itself, a TTL after the last renewal
+// it received or the last put replicated to it. So a renewed registration is put again once half of its TTL
+// has passed since the last put, a registry server that joins later learns the service then.
+type Leases struct {
+	sync.Mutex
+	id        string                     // the id of the local registry server
+	handler   Handler                    // adds the services to their groups and removes them
+	broadcast func(payload []byte) error // replicates a change to the other registry servers
+	leases    map[string]*lease
+	closed    bool
+
+	// clock is the Lamport clock of the changes, it witnesses the versions of the replicated changes and the
+	// Lamport time of the serf user events, so a change is newer than all the changes the registry server has seen.
+	clock uint64
+}
+
+// NewLeases creates a new Leases object.
+// id is the id of the local registry server, the changes it broadcasts are not applied twice.
+// broadcast replicates the changes to the other registry servers, it can be nil.
+func NewLeases(id string, handler Handler, broadcast func(payload []byte) error) *Leases {
+	return &Leases{
+		id:        id,
+		handler:   handler,
+		broadcast: broadcast,
+		leases:    make(map[string]*lease),
+	}
+}
+
+// Register registers the service of a member for ttl, DefaultLeaseTTL is used if ttl is not greater than 0.
+// If the service is registered already, its registration is updated and renewed,
+// a service registered again in another group leaves its previous group.
+// The ids of the services are unique across the groups.
+// The registered services are placed with DefaultReplicas virtual nodes, and their meta can't set the reserved tags.
+func (l *Leases) Register(m *Member, ttl time.Duration) error {
+	if len(m.Service.Id) == 0 {
+		return ErrMemberIdEmpty
+	}
+	// The virtual nodes are not chosen by the services, a large number of them would exhaust the memory.
+	if m.Replicas != DefaultReplicas {
+		return ErrReplicasParam
+	}
+	if err := checkTags(m.Service.Meta); err != nil {
+		return err
+	}
+	if len(m.Service.Group) == 0 {
+		return ErrGroupNameEmpty
+	}
+	if len(m.Service.Addr) == 0 {
+		return ErrServiceAddrEmpty
+	}
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	l.Lock()
+	// A registration can't take the place of a service registered over serf.
+	if _, ok := l.live(m.Service.Id); !ok {
+		if _, err := findMember(m.Service.Group, m.Service.Id); err == nil {
+			l.Unlock()
+			return ErrServiceConflict
+		}
+	}
+	version := l.next()
+	err := l.put(m, ttl, version, l.id)
+	l.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return l.replicate(&leaseMessage{Version: version, Op: leasePut, Id: m.Service.Id, Member: m, TTL: ttl})
+}
+
+// Renew renews the registration of a service for its TTL, it returns the member and the TTL of the service.
+// The registration is put to the other registry servers again only if half of its TTL has passed since the last put.
+func (l *Leases) Renew(id string) (*Member, time.Duration, error) {
+	l.Lock()
+	s, ok := l.live(id)
+	if !ok {
+		l.Unlock()
+		return nil, 0, ErrServiceNotFound
+	}
+
+	renewed := &lease{member: s.member, ttl: s.ttl, replicated: s.replicated, version: s.version, origin: s.origin}
+	var msg *leaseMessage
+	if time.Since(s.replicated) >= s.ttl/2 {
+		renewed.replicated, renewed.version, renewed.origin = time.Now(), l.next(), l.id
+		msg = &leaseMessage{Version: renewed.version, Op: leasePut, Id: id, Member: s.member, TTL: s.ttl}
+	}
+	l.reset(id, renewed)
+	l.Unlock()
+
+	if msg == nil {
+		return s.member, s.ttl, nil
+	}
+	return s.member, s.ttl, l.replicate(msg)
+}
+
+// Deregister removes a registered service from its group.
+func (l *Leases) Deregister(id string) error {
+	l.Lock()
+	s, ok := l.live(id)
+	if !ok {
+		l.Unlock()
+		return ErrServiceNotFound
+	}
+	version := l.next()
+	err := l.remove(id, version, l.id)
+	l.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return l.replicate(&leaseMessage{Version: version, Op: leaseDelete, Id: id, TTL: s.ttl})
+}
+
+// Close stops the timers and forgets all the registrations, the services are not removed from their groups.
+func (l *Leases) Close() {
+	l.Lock()
+	defer l.Unlock()
+	l.closed = true
+	for _, s := range l.leases {
+		s.timer.Stop()
+	}
+	l.leases = make(map[string]*lease)
+}
+
+// witness advances the clock of the changes to a Lamport time seen in the cluster.
+func (l *Leases) witness(ltime uint64) {
+	l.Lock()
+	defer l.Unlock()
+	l.advance(ltime)
+}
+
+// apply applies a change broadcast by a registry server in a user event of the Lamport time ltime,
+// unless a later change of the service is applied.
+func (l *Leases) apply(ltime uint64, payload []byte) error {
+	msg := &leaseMessage{}
+	if err := json.Unmarshal(payload, msg); err != nil {
+		return err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.advance(ltime)
+	l.advance(msg.Version)
+
+	// The changes of the local registry server are applied before they are broadcast.
+	if msg.Origin == l.id {
+		return nil
+	}
+	if l.closed || !l.leases[msg.Id].newer(msg.Version, msg.Origin) {
+		return nil
+	}
+	if msg.TTL <= 0 {
+		msg.TTL = DefaultLeaseTTL
+	}
+
+	switch msg.Op {
+	case leasePut:
+		if msg.Member == nil {
+			return ErrRequestBody
+		}
+		return l.put(msg.Member, msg.TTL, msg.Version, msg.Origin)
+	case leaseDelete:
+		return l.remove(msg.Id, msg.Version, msg.Origin)
+	}
+	return nil
+}
+
+// replicate broadcasts a change of the local registry server to the other registry servers.
+func (l *Leases) replicate(msg *leaseMessage) error {
+	if l.broadcast == nil {
+		return nil
+	}
+	msg.Origin = l.id
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return l.broadcast(payload)
+}
+
+// live returns the registration of a service that is not deregistered, the caller must hold the lock.
+func (l *Leases) live(id string) (*lease, bool) {
+	s, ok := l.leases[id]
+	if !ok || s.member == nil {
+		return nil, false
+	}
+	return s, true
+}
+
+// advance advances the clock of the changes to ltime if it is later, the caller must hold the lock.
+func (l *Leases) advance(ltime uint64) {
+	if ltime > l.clock {
+		l.clock = ltime
+	}
+}
+
+// next returns the version of a new change, the caller must hold the lock.
+func (l *Leases) next() uint64 {
+	l.clock++
+	return l.clock
+}
+
+// put adds or updates a registration and restarts its timer, the caller must hold the lock.
+// The handler is notified only if the service is added or changed.
+func (l *Leases) put(m *Member, ttl time.Duration, version uint64, origin string) error {
+	id := m.Service.Id
+	prev, ok := l.live(id)
+	if ok && prev.member.Service.Group != m.Service.Group {
+		// The service moves to another group, it leaves the previous one first.
+		if err := l.handler.OnMemberLeave(prev.member); err != nil {
+			return err
+		}
+		ok = false
+	}
+
+	var err error
+	if !ok {
+		err = l.handler.OnMemberJoin(m)
+	} else if !sameMember(prev.member, m) {
+		err = l.handler.OnMemberUpdate(m)
+	}
+	if err != nil {
+		return err
+	}
+
+	l.reset(id, &lease{member: m, ttl: ttl, replicated: time.Now(), version: version, origin: origin})
+	return nil
+}
+
+// remove removes a service from its group and keeps its deregistration for its TTL,
+// the caller must hold the lock.
+func (l *Leases) remove(id string, version uint64, origin string) error {
+	var err error
+	if s, ok := l.live(id); ok {
+		err = l.handler.OnMemberLeave(s.member)
+	}
+	l.reset(id, &lease{version: version, origin: origin})
+	return err
+}
+
+// reset replaces the registration of a service and starts its timer, the caller must hold the lock.
+func (l *Leases) reset(id string, s *lease) {
+	if prev, ok := l.leases[id]; ok {
+		prev.timer.Stop()
+		if s.ttl == 0 {
+			s.ttl = prev.ttl
+		}
+	}
+	if s.ttl <= 0 {
+		s.ttl = DefaultLeaseTTL
+	}
+	s.timer = time.AfterFunc(s.ttl, func() {
+		l.expire(id, s)
+	})
+	l.leases[id] = s
+}
+
+// expire removes a service that is not renewed within its TTL, or forgets a deregistered service.
+// Nothing is done if the service is changed meanwhile.
+func (l *Leases) expire(id string, s *lease) {
+	l.Lock()
+	defer l.Unlock()
+	if l.closed || l.leases[id] != s {
+		return
+	}
+
+	delete(l.leases, id)
+	if s.member == nil {
+		return
+	}
+	log.Printf("[INFO] a registration expired, id:%s, group:%s, ttl:%s\n", id, s.member.Service.Group, s.ttl)
+	if err := l.handler.OnMemberLeave(s.member); err != nil {
+		log.Printf("[ERROR] remove expired registration of %s err:%s\n", id, err.Error())
+	}
+}
+
+// checkTags returns ErrRequestBody if the tags of a registration set a tag reserved by the registry,
+// or the advertised http address of the registry servers.
+func checkTags(tags map[string]string) error {
+	for k := range tags {
+		if IsReservedTag(k) || k == TagHttp {
+			return ErrRequestBody
+		}
+	}
+	return nil
+}
+
+// sameMember returns true if the services of two members are the same.
+func sameMember(a *Member, b *Member) bool {
+	pa, err := a.Marshal()
+	if err != nil {
+		return false
+	}
+	pb, err := b.Marshal()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(pa, pb)
+}
@@ -15,6 +15,14 @@ type Service struct {
 
 	// The service address provided to the client.
 	Addr string `json:"addr"`
+
+	// Meta is the extra information of the service, such as version, zone and protocol.
+	// It is made of the member's tags except the reserved ones (group, addr and replicas).
+	Meta map[string]string `json:"meta,omitempty"`
+
+	// Ejected is true if the service is temporarily ejected for the failures reported by the clients,
+	// its keys are assigned to the next service on the ring until the ejection ends.
+	Ejected bool `json:"ejected,omitempty"`
 }
 
 // NewService creates a new service object.
@@ -107,6 +115,12 @@ func (m *Member) SetTag(key string, val string) {
 		m.Service.Group = val
 	} else if key == TagReplicas {
 		m.Replicas = val
+	} else {
+		// Other tags are exposed to the clients as the service's meta.
+		if m.Service.Meta == nil {
+			m.Service.Meta = make(map[string]string)
+		}
+		m.Service.Meta[key] = val
 	}
 }
 
@@ -158,9 +172,21 @@ func (m *Member) GetTags() map[string]string {
 	for k, v := range m.tags {
 		clone[k] = v
 	}
+
+	// The meta set on the service directly is gossiped as tags as well.
+	for k, v := range m.Service.Meta {
+		if !IsReservedTag(k) {
+			clone[k] = v
+		}
+	}
 	return clone
 }
 
+// IsReservedTag returns true if the tag is used by the registry itself and is not part of the service's meta.
+func IsReservedTag(key string) bool {
+	return key == TagGroup || key == TagAddr || key == TagReplicas
+}
+
 // Marshal returns the JSON encoding of this Member object.
 func (m *Member) Marshal() ([]byte, error) {
 	m.Lock()
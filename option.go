@@ -2,6 +2,7 @@ package registry
 
 import (
 	"os"
+	"time"
 
 	"github.com/rs/xid"
 )
@@ -24,11 +25,60 @@ type Option struct {
 	// If there are more than one, separate them with commas, such as "192.168.1.101:7370,192.168.1.102:7370".
 	Registries string
 
-	// Addr is the address used for service discovery.
+	// Rpc enables the gRPC api for service discovery, it is enabled by default.
+	Rpc bool
+
+	// Addr is the address used for service discovery by the gRPC api.
 	Addr string
 
-	// Advertise is the address that will be advertised to clients for service discovery.
+	// Advertise is the address of the gRPC api that will be advertised to clients for service discovery.
 	Advertise string
+
+	// HttpAddr is the address of the http api for service discovery, the http api is enabled if it is set.
+	HttpAddr string
+
+	// HttpAdvertise is the address of the http api that will be advertised to clients, it is HttpAddr if empty.
+	HttpAdvertise string
+
+	// SSEHeartbeat is the interval of the heartbeats of the SSE watch streams of the http api,
+	// which keep the proxies from closing the idle streams.
+	SSEHeartbeat time.Duration
+
+	// Health enables the standard gRPC health service (grpc.health.v1) on the discovery api.
+	// The registry is reported as serving while it is a member of the serf cluster.
+	Health bool
+
+	// Reflection enables the gRPC server reflection on the discovery api, so that tools like grpcurl can be used.
+	Reflection bool
+
+	// CertFile and KeyFile are the PEM encoded certificate and key of the discovery api.
+	// If they are set, the discovery api is served over TLS. They are reloaded when the files are modified.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile is the PEM encoded CA used to verify the clients' certificates.
+	// If it is set, the clients must present a certificate signed by it (mutual TLS).
+	ClientCAFile string
+
+	// Authenticator authenticates the requests of the discovery api, all requests are accepted if it is nil.
+	Authenticator Authenticator
+
+	// EjectThreshold is the number of failures reported by the clients within EjectWindow that ejects a service,
+	// the keys of an ejected service are assigned to the next service on the ring for EjectCooldown.
+	// The ejection is disabled if it is 0, which is the default. The failures can be reported by any client
+	// that can call the discovery api, so the Authenticator should be set when the ejection is enabled.
+	// The failures are counted by each registry server, the ejections are replicated to the other registry servers.
+	EjectThreshold int
+	EjectWindow    time.Duration
+	EjectCooldown  time.Duration
+
+	// EjectMaxPercent is the maximum percentage of the services of a group that can be ejected at the same time,
+	// a service that reaches the threshold is not ejected beyond it.
+	EjectMaxPercent int
+
+	// RegisterGrace is how long a service registered by the Register stream of the gRPC api stays registered
+	// after its stream breaks, so that it can reconnect to any registry server without leaving its group.
+	RegisterGrace time.Duration
 }
 
 // IOption represents a function that modifies the Option.
@@ -50,6 +100,28 @@ func OptAddr(addr string) IOption {
 	}
 }
 
+// OptRpc sets whether the gRPC api is enabled option.
+func OptRpc(enable bool) IOption {
+	return func(o *Option) {
+		o.Rpc = enable
+	}
+}
+
+// OptHttp sets the listen and advertised addresses of the http api option, the http api is enabled if addr is set.
+func OptHttp(addr string, advertise string) IOption {
+	return func(o *Option) {
+		o.HttpAddr = addr
+		o.HttpAdvertise = advertise
+	}
+}
+
+// OptSSEHeartbeat sets the interval of the heartbeats of the SSE watch streams option.
+func OptSSEHeartbeat(interval time.Duration) IOption {
+	return func(o *Option) {
+		o.SSEHeartbeat = interval
+	}
+}
+
 // OptAdvertise sets the advertised address for service discovery option.
 func OptAdvertise(addr string) IOption {
 	return func(o *Option) {
@@ -84,6 +156,65 @@ func OptRegistries(registries string) IOption {
 	}
 }
 
+// OptHealth sets whether the gRPC health service is enabled option.
+func OptHealth(enable bool) IOption {
+	return func(o *Option) {
+		o.Health = enable
+	}
+}
+
+// OptReflection sets whether the gRPC server reflection is enabled option.
+func OptReflection(enable bool) IOption {
+	return func(o *Option) {
+		o.Reflection = enable
+	}
+}
+
+// OptTLS sets the certificate and key files of the discovery api option.
+func OptTLS(certFile string, keyFile string) IOption {
+	return func(o *Option) {
+		o.CertFile = certFile
+		o.KeyFile = keyFile
+	}
+}
+
+// OptClientCA sets the CA file used to verify the clients' certificates option.
+func OptClientCA(file string) IOption {
+	return func(o *Option) {
+		o.ClientCAFile = file
+	}
+}
+
+// OptAuthenticator sets the authenticator of the discovery api option.
+func OptAuthenticator(auth Authenticator) IOption {
+	return func(o *Option) {
+		o.Authenticator = auth
+	}
+}
+
+// OptEjection sets the failure threshold, the window of the failures and the cooldown of the ejected services option.
+func OptEjection(threshold int, window time.Duration, cooldown time.Duration) IOption {
+	return func(o *Option) {
+		o.EjectThreshold = threshold
+		o.EjectWindow = window
+		o.EjectCooldown = cooldown
+	}
+}
+
+// OptEjectMaxPercent sets the maximum percentage of the ejected services of a group option.
+func OptEjectMaxPercent(percent int) IOption {
+	return func(o *Option) {
+		o.EjectMaxPercent = percent
+	}
+}
+
+// OptRegisterGrace sets the grace period of the services registered by the Register stream option.
+func OptRegisterGrace(grace time.Duration) IOption {
+	return func(o *Option) {
+		o.RegisterGrace = grace
+	}
+}
+
 // DefaultOption returns the default options for registering a server.
 func DefaultOption() *Option {
 	hostname, _ := os.Hostname()
@@ -91,5 +222,14 @@ func DefaultOption() *Option {
 		Id:            hostname + "-" + xid.New().String(),
 		Bind:          ":7370",
 		BindAdvertise: ":7370",
+		Rpc:           true,
+		Health:        true,
+		Reflection:    true,
+
+		SSEHeartbeat:    sseHeartbeat,
+		EjectWindow:     10 * time.Second,
+		EjectCooldown:   30 * time.Second,
+		EjectMaxPercent: 50,
+		RegisterGrace:   registerGrace,
 	}
 }
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+package registry
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// ejectEvent is the name of the serf user events that replicate the ejections.
+const ejectEvent = "registry-eject"
+
+// ejectMessage is an ejection replicated to the other registry servers.
+type ejectMessage struct {
+	Origin   string        `json:"origin"`   // The id of the registry server that ejected the service.
+	Group    string        `json:"group"`    // The group of the service.
+	Id       string        `json:"id"`       // The id of the service.
+	Cooldown time.Duration `json:"cooldown"` // How long the service is ejected, the clocks of the servers are not compared.
+}
+
+// outlier is the failures of a service reported by the clients.
+type outlier struct {
+	failures []time.Time // The failures reported within the window.
+	until    time.Time   // The end of the ejection, zero if the service is not ejected.
+	timer    *time.Timer // Restores the service at the end of the ejection.
+}
+
+// Outliers tracks the failures of the services reported by the clients, and ejects the services
+// that fail too often. The keys of an ejected service are assigned to the next service on the ring
+// until the cooldown ends. The failures are counted by each registry server, the ejections are replicated
+// to the other registry servers by serf user events, so that all of them assign the keys the same way.
+type Outliers struct {
+	sync.Mutex
+	threshold  int
+	window     time.Duration
+	cooldown   time.Duration
+	maxPercent int
+	services   map[string]map[string]*outlier
+
+	// onChange is called when a service is ejected or restored.
+	onChange func(group string, id string)
+
+	id        string                     // the id of the local registry server, set by replicate
+	broadcast func(payload []byte) error // replicates an ejection to the other registry servers, nil if not replicated
+}
+
+// NewOutliers creates a new Outliers object.
+// A service is ejected for cooldown once threshold failures are reported within window,
+// the ejection is disabled if threshold is not greater than 0.
+// At most maxPercent percent of the services of a group are ejected at the same time.
+// onChange is called without the lock when a service is ejected or restored, it can be nil.
+func NewOutliers(threshold int, window time.Duration, cooldown time.Duration, maxPercent int, onChange func(group string, id string)) *Outliers {
+	return &Outliers{
+		threshold:  threshold,
+		window:     window,
+		cooldown:   cooldown,
+		maxPercent: maxPercent,
+		services:   make(map[string]map[string]*outlier),
+		onChange:   onChange,
+	}
+}
+
+// Report records a failure of a service, and ejects it if it reaches the threshold.
+// It returns true if the service is ejected.
+func (o *Outliers) Report(group string, id string) bool {
+	if o == nil || o.threshold <= 0 {
+		return false
+	}
+
+	// The size of the group is read before the lock, the ring must not be locked while holding the lock,
+	// since the ring is walked while the ejections are checked.
+	size := 0
+	if ring, err := getRing(group); err == nil {
+		size = ring.Len()
+	}
+
+	o.Lock()
+	now := time.Now()
+	if o.services[group] == nil {
+		o.services[group] = make(map[string]*outlier)
+	}
+	s, ok := o.services[group][id]
+	if !ok {
+		s = &outlier{}
+		o.services[group][id] = s
+	}
+
+	// The failures reported while the service is ejected don't extend the ejection.
+	if now.Before(s.until) {
+		o.Unlock()
+		return true
+	}
+
+	failures := make([]time.Time, 0, len(s.failures)+1)
+	for _, failure := range s.failures {
+		if now.Sub(failure) < o.window {
+			failures = append(failures, failure)
+		}
+	}
+	s.failures = append(failures, now)
+	if len(s.failures) < o.threshold || !o.ejectable(group, now, size) {
+		o.Unlock()
+		return false
+	}
+
+	s.failures = nil
+	o.eject(group, id, s, now.Add(o.cooldown))
+	broadcast := o.broadcast
+	o.Unlock()
+
+	if broadcast != nil {
+		msg := &ejectMessage{Origin: o.id, Group: group, Id: id, Cooldown: o.cooldown}
+		if payload, err := json.Marshal(msg); err != nil {
+			log.Printf("[ERROR] marshal ejection of %s err:%s\n", id, err.Error())
+		} else if err := broadcast(payload); err != nil {
+			log.Printf("[ERROR] replicate ejection of %s err:%s\n", id, err.Error())
+		}
+	}
+	if o.onChange != nil {
+		o.onChange(group, id)
+	}
+	return true
+}
+
+// eject ejects a service until the time until, the caller must hold the lock.
+func (o *Outliers) eject(group string, id string, s *outlier, until time.Time) {
+	s.until = until
+	s.timer = time.AfterFunc(time.Until(until), func() {
+		o.restore(group, id, s)
+	})
+}
+
+// replicate makes the ejections replicated to the other registry servers by broadcast,
+// id is the id of the local registry server, its own ejections are not applied twice.
+func (o *Outliers) replicate(id string, broadcast func(payload []byte) error) {
+	o.Lock()
+	defer o.Unlock()
+	o.id = id
+	o.broadcast = broadcast
+}
+
+// apply applies an ejection broadcast by a registry server, unless the service is ejected already.
+// The service is ejected for the cooldown of the message from the time it is received.
+func (o *Outliers) apply(payload []byte) error {
+	msg := &ejectMessage{}
+	if err := json.Unmarshal(payload, msg); err != nil {
+		return err
+	}
+
+	o.Lock()
+	if o.threshold <= 0 || msg.Origin == o.id || msg.Cooldown <= 0 {
+		o.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if o.services[msg.Group] == nil {
+		o.services[msg.Group] = make(map[string]*outlier)
+	}
+	s, ok := o.services[msg.Group][msg.Id]
+	if !ok {
+		s = &outlier{}
+		o.services[msg.Group][msg.Id] = s
+	}
+	if now.Before(s.until) {
+		o.Unlock()
+		return nil
+	}
+	s.failures = nil
+	o.eject(msg.Group, msg.Id, s, now.Add(msg.Cooldown))
+	o.Unlock()
+
+	if o.onChange != nil {
+		o.onChange(msg.Group, msg.Id)
+	}
+	return nil
+}
+
+// ejectable returns true if one more service of the group of size services can be ejected within maxPercent,
+// the caller must hold the lock.
+func (o *Outliers) ejectable(group string, now time.Time, size int) bool {
+	ejected := 1
+	for _, s := range o.services[group] {
+		if now.Before(s.until) {
+			ejected++
+		}
+	}
+	return ejected*100 <= size*o.maxPercent
+}
+
+// restore ends the ejection of a service, unless the service has been removed meanwhile.
+func (o *Outliers) restore(group string, id string, s *outlier) {
+	o.Lock()
+	if o.services[group][id] != s {
+		o.Unlock()
+		return
+	}
+	o.remove(group, id)
+	o.Unlock()
+
+	if o.onChange != nil {
+		o.onChange(group, id)
+	}
+}
+
+// Ejected returns true if the service is ejected.
+func (o *Outliers) Ejected(group string, id string) bool {
+	if o == nil {
+		return false
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	s, ok := o.services[group][id]
+	return ok && time.Now().Before(s.until)
+}
+
+// ejected returns the ids of the ejected services of the group, nil if there is none.
+func (o *Outliers) ejected(group string) map[string]bool {
+	if o == nil {
+		return nil
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	var ids map[string]bool
+	now := time.Now()
+	for id, s := range o.services[group] {
+		if now.Before(s.until) {
+			if ids == nil {
+				ids = make(map[string]bool)
+			}
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// Remove forgets the failures of a service, it is called when the service leaves.
+func (o *Outliers) Remove(group string, id string) {
+	if o == nil {
+		return
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	o.remove(group, id)
+}
+
+// remove deletes a service and stops its timer, the caller must hold the lock.
+func (o *Outliers) remove(group string, id string) {
+	s, ok := o.services[group][id]
+	if !ok {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	delete(o.services[group], id)
+	if len(o.services[group]) == 0 {
+		delete(o.services, group)
+	}
+}
+
+// Close forgets all the failures and stops the timers.
+func (o *Outliers) Close() {
+	if o == nil {
+		return
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	for group, services := range o.services {
+		for id := range services {
+			o.remove(group, id)
+		}
+	}
+}
+
+// mark sets the ejection state of the service.
+func (o *Outliers) mark(service *Service) *Service {
+	service.Ejected = o.Ejected(service.Group, service.Id)
+	return service
+}
+
+// owner returns the id and payload of the service that owns the key in the group.
+// If the owner is ejected, the keys move to the next service on the ring that is not ejected,
+// the same as the owner had left. If all the services are ejected, the owner is returned.
+func (o *Outliers) owner(group string, ring *Ring, key string) (string, []byte, error) {
+	// The ejections are collected before the ring is walked, the lock is not taken while the ring is locked.
+	ejected := o.ejected(group)
+	element, err := ring.Owner(key, func(id string) bool {
+		return ejected[id]
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return element.Key, element.Payload, nil
+}
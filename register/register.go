@@ -36,6 +36,12 @@ func (r *Register) SetHandler(h registry.Handler) {
 	r.handler = h
 }
 
+// SetTag sets extra information of the service, such as version, zone and protocol.
+// The tags are exposed to the clients as the meta of the service, it should be called before Start().
+func (r *Register) SetTag(key string, val string) {
+	r.member.SetTag(key, val)
+}
+
 // Start starts the service registration process.
 func (r *Register) Start() error {
 	r.serf = registry.NewSerf(r.member)
@@ -2,21 +2,54 @@ package registry
 
 import (
 	"log"
+	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/werbenhu/chash"
 )
 
 const (
-	registryName    = "registry-group" // Default group name for registry service
+	RegistryGroup   = "registry-group" // Group name of the registry servers
 	DefaultReplicas = "10000"          // Default number of replicas to virtualize a service
 )
 
+var (
+	// rings keeps the Ring of each group by name, a group is created when its first service joins.
+	rings sync.Map
+)
+
+// GroupInfo is the summary of a group.
+type GroupInfo struct {
+	// The group name.
+	Name string `json:"name"`
+
+	// The number of services in the group.
+	Members int `json:"members"`
+
+	// The number of replicated elements of each service that are virtualized on the ring.
+	Replicas int `json:"replicas"`
+
+	// The last time a service joined, left or was updated.
+	Updated time.Time `json:"updated"`
+}
+
+// apiServer is an enabled api and the address it listens to.
+type apiServer struct {
+	Api
+	addr string
+}
+
 // Registry is the registry server object
 type Registry struct {
-	opt  *Option
-	serf Discovery
-	api  Api
+	opt      *Option
+	serf     Discovery
+	apis     []*apiServer // the enabled apis, the gRPC api and the http api
+	outliers *Outliers    // the services ejected for the failures reported by the clients
+	leases   *Leases      // the services registered through the apis instead of serf
+	closed   atomic.Bool
 }
 
 // New creates a new registry object that can start a registry server when calling Serve().
@@ -33,39 +66,97 @@ func New(opts []IOption) *Registry {
 	if len(s.opt.Advertise) == 0 {
 		s.opt.Advertise = s.opt.Addr
 	}
+	if len(s.opt.HttpAdvertise) == 0 {
+		s.opt.HttpAdvertise = s.opt.HttpAddr
+	}
+
+	// All the apis share the ejected services, so that they assign the keys the same way.
+	s.outliers = NewOutliers(s.opt.EjectThreshold, s.opt.EjectWindow, s.opt.EjectCooldown, s.opt.EjectMaxPercent, s.onEjection)
+	s.outliers.replicate(s.opt.Id, s.broadcastEjection)
+	s.leases = NewLeases(s.opt.Id, s, s.broadcastLease)
+	s.apis = make([]*apiServer, 0, 2)
+
+	// The registry servers are discovered by the address of the gRPC api, which is empty if it is disabled.
+	advertise := ""
+	if s.opt.Rpc {
+		advertise = s.opt.Advertise
+		s.apis = append(s.apis, &apiServer{Api: newRpcServer(s.opt, s.outliers, s.leases), addr: s.opt.Addr})
+	}
+	if len(s.opt.HttpAddr) > 0 {
+		s.apis = append(s.apis, &apiServer{Api: newHttp(s.opt, s.outliers, s.leases), addr: s.opt.HttpAddr})
+	}
 
-	s.api = NewRpcServer()
-	s.serf = NewSerf(NewMember(
+	member := NewMember(
 		s.opt.Id,
 		s.opt.Bind,
 		s.opt.BindAdvertise,
 		s.opt.Registries,
-		registryName,
-		s.opt.Advertise,
-	))
+		RegistryGroup,
+		advertise,
+	)
+	if len(s.opt.HttpAdvertise) > 0 {
+		member.SetTag(TagHttp, s.opt.HttpAdvertise)
+	}
+	s.serf = NewSerf(member)
 	s.serf.SetHandler(s)
 	return s
 }
 
-// Serve runs the registry server
+// setServing sets the status reported by the health services of the apis.
+func (s *Registry) setServing(serving bool) {
+	for _, api := range s.apis {
+		if rpc, ok := api.Api.(*RpcServer); ok {
+			rpc.SetServing(serving)
+		}
+	}
+}
+
+// Serve runs the registry server, it blocks until all the apis are stopped by Close.
 func (s *Registry) Serve() {
+	if len(s.apis) == 0 {
+		panic(ErrNoApiEnabled)
+	}
 	if err := s.serf.Start(); err != nil {
 		panic(err)
 	}
-	if err := s.api.Start(s.opt.Addr); err != nil {
-		panic(err)
+	// The changes of the registrations made by the registry server are newer than the ones made
+	// before it joined, even if it restarts.
+	s.leases.witness(s.serf.EventTime())
+
+	// The registry serves clients once it is a member of the serf cluster, see onLocalMember.
+	errs := make(chan error, len(s.apis))
+	for _, api := range s.apis {
+		go func(api *apiServer) {
+			errs <- api.Start(api.addr)
+		}(api)
+	}
+
+	// The errors of the apis stopped by Close, such as the closed listener of the http api, are expected.
+	for range s.apis {
+		if err := <-errs; err != nil && !s.closed.Load() {
+			panic(err)
+		}
 	}
 }
 
 // Close closes the registry server
 func (s *Registry) Close() {
+	s.closed.Store(true)
+
+	// Stop serving before leaving the serf cluster, so that load balancers drain the registry first.
+	s.setServing(false)
 	if s.serf != nil {
 		s.serf.Stop()
 	}
-	if s.api != nil {
-		s.api.Stop()
+	for _, api := range s.apis {
+		api.Stop()
 	}
-	chash.RemoveAllGroup() // Remove all groups from chash
+	s.outliers.Close()
+	s.leases.Close()
+	rings.Range(func(key any, val any) bool {
+		rings.Delete(key)
+		return true
+	})
 	log.Printf("[DEBUG] registry server is closed.\n")
 }
 
@@ -73,24 +164,109 @@ func (s *Registry) Close() {
 func (s *Registry) OnMemberJoin(m *Member) error {
 	log.Printf("[INFO] a new member joined, id:%s, bind:%s, group:%s, service:%s\n",
 		m.Id, m.Bind, m.Service.Group, m.Service.Addr)
-	return s.insert(m)
+	if err := s.insert(m); err != nil {
+		return err
+	}
+	s.onLocalMember(m, true)
+
+	// Notify the apis so that they can push the change to the watchers.
+	return s.notify(func(h Handler) error {
+		return h.OnMemberJoin(m)
+	})
 }
 
 // OnMemberLeave is triggered when a service leaves
 func (s *Registry) OnMemberLeave(m *Member) error {
 	log.Printf("[INFO] a new member left, id:%s, bind:%s, group:%s, service:%s\n",
 		m.Id, m.Bind, m.Service.Group, m.Service.Addr)
-	return s.delete(m)
+	if err := s.delete(m); err != nil {
+		return err
+	}
+	s.onLocalMember(m, false)
+	s.outliers.Remove(m.Service.Group, m.Service.Id)
+	return s.notify(func(h Handler) error {
+		return h.OnMemberLeave(m)
+	})
 }
 
 // OnMemberUpdate is triggered when a service is updated
 func (s *Registry) OnMemberUpdate(m *Member) error {
 	log.Printf("[INFO] a new member updated, id:%s, bind:%s, group:%s, service:%s\n",
 		m.Id, m.Bind, m.Service.Group, m.Service.Addr)
-	return s.insert(m)
+	if err := s.insert(m); err != nil {
+		return err
+	}
+	return s.notify(func(h Handler) error {
+		return h.OnMemberUpdate(m)
+	})
+}
+
+// onLocalMember sets the status of the health services by the serf state of the local registry server,
+// it is serving while it is a member of the serf cluster, and not serving once it left or failed.
+func (s *Registry) onLocalMember(m *Member, alive bool) {
+	if m.Id != s.opt.Id || m.Service.Group != RegistryGroup {
+		return
+	}
+	s.setServing(alive && !s.closed.Load())
+}
+
+// onEjection notifies the apis that a service is ejected or restored,
+// so that the watchers receive the service with its ejection state in an update event.
+func (s *Registry) onEjection(group string, id string) {
+	m, err := findMember(group, id)
+	if err != nil {
+		// The service has left meanwhile.
+		return
+	}
+	s.outliers.mark(&m.Service)
+	log.Printf("[INFO] a member ejection changed, id:%s, group:%s, service:%s, ejected:%t\n",
+		m.Id, m.Service.Group, m.Service.Addr, m.Service.Ejected)
+
+	err = s.notify(func(h Handler) error {
+		return h.OnMemberUpdate(m)
+	})
+	if err != nil {
+		log.Printf("[ERROR] notify ejection of %s err:%s\n", id, err.Error())
+	}
+}
+
+// OnUserEvent is triggered when a custom event is broadcast by a registry server,
+// the registrations and the ejections of the services are replicated by the events.
+func (s *Registry) OnUserEvent(name string, ltime uint64, payload []byte) error {
+	switch name {
+	case leaseEvent:
+		return s.leases.apply(ltime, payload)
+	case ejectEvent:
+		return s.outliers.apply(payload)
+	}
+	return nil
+}
+
+// broadcastLease replicates a change of the registrations to the other registry servers.
+func (s *Registry) broadcastLease(payload []byte) error {
+	return s.serf.UserEvent(leaseEvent, payload)
+}
+
+// broadcastEjection replicates an ejection to the other registry servers.
+func (s *Registry) broadcastEjection(payload []byte) error {
+	return s.serf.UserEvent(ejectEvent, payload)
 }
 
-// delete removes a service from chash
+// notify calls fn on every api that is a Handler, all of them are notified even if one fails,
+// and the first error is returned.
+func (s *Registry) notify(fn func(h Handler) error) error {
+	var first error
+	for _, api := range s.apis {
+		if h, ok := api.Api.(Handler); ok {
+			if err := fn(h); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// delete removes a service from the ring of its group
 func (s *Registry) delete(m *Member) error {
 	if len(m.Service.Group) == 0 {
 		return ErrGroupNameEmpty
@@ -101,14 +277,11 @@ func (s *Registry) delete(m *Member) error {
 		return ErrReplicasParam
 	}
 
-	group, _ := chash.CreateGroup(m.Service.Group, replicas)
-	if err := group.Delete(m.Service.Id); err != nil {
-		return err
-	}
+	createRing(m.Service.Group, replicas).Delete(m.Service.Id)
 	return nil
 }
 
-// insert adds a service to chash
+// insert adds a service to the ring of its group
 func (s *Registry) insert(m *Member) error {
 	if len(m.Service.Group) == 0 {
 		return ErrGroupNameEmpty
@@ -124,23 +297,41 @@ func (s *Registry) insert(m *Member) error {
 		return err
 	}
 
-	group, _ := chash.CreateGroup(m.Service.Group, replicas)
-	if err := group.Upsert(m.Service.Id, payload); err != nil {
-		return err
-	}
+	createRing(m.Service.Group, replicas).Upsert(m.Service.Id, payload)
 	return nil
 }
 
+// getRing returns the Ring of a group, chash.ErrGroupNotFound is returned if the group does not exist.
+func getRing(name string) (*Ring, error) {
+	ring, ok := rings.Load(name)
+	if !ok {
+		return nil, chash.ErrGroupNotFound
+	}
+	return ring.(*Ring), nil
+}
+
+// createRing returns the Ring of a group, the group is created with replicas if it does not exist.
+// The replicas of an existing group are not changed.
+func createRing(name string, replicas int) *Ring {
+	// Load first, so that a Ring is allocated only when the group is created.
+	if ring, ok := rings.Load(name); ok {
+		return ring.(*Ring)
+	}
+	ring, _ := rings.LoadOrStore(name, NewRing(replicas))
+	return ring.(*Ring)
+}
+
 // Match uses a consistent hashing algorithm to assign a service to a key.
+// If the service is ejected, the key is assigned to the next service on the ring.
 func (s *Registry) Match(groupName string, key string) (*Service, error) {
-	// Get the group associated with the group name.
-	group, err := chash.GetGroup(groupName)
+	// Get the ring associated with the group name.
+	ring, err := getRing(groupName)
 	if err != nil {
 		return nil, err
 	}
 
-	// Find the element in the group that matches the key.
-	_, payload, err := group.Match(key)
+	// Find the element in the group that matches the key, skipping the ejected services.
+	_, payload, err := s.outliers.owner(groupName, ring, key)
 	if err != nil {
 		return nil, err
 	}
@@ -155,19 +346,149 @@ func (s *Registry) Match(groupName string, key string) (*Service, error) {
 	return &m.Service, nil
 }
 
+// MatchN returns up to n distinct services for a key, the first one is the owner of the key on the ring,
+// followed by the services that would own the key in turn if the previous ones left.
+// It can be used to replicate the data of a key and fail over to the next service without rehashing.
+// The ejected services are not skipped, they are marked Ejected.
+func (s *Registry) MatchN(groupName string, key string, n int) ([]*Service, error) {
+	return matchN(s.outliers, groupName, key, n)
+}
+
+// matchN walks the ring of the group to find up to n distinct services for a key.
+func matchN(o *Outliers, groupName string, key string, n int) ([]*Service, error) {
+	ring, err := getRing(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, err := ring.MatchN(key, n)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]*Service, 0, len(elements))
+	for _, element := range elements {
+		m := &Member{}
+		if err := m.Unmarshal(element.Payload); err != nil {
+			return nil, err
+		}
+		services = append(services, o.mark(&m.Service))
+	}
+	return services, nil
+}
+
+// shard is a service with the keys assigned to it by matchMany.
+type shard struct {
+	Service *Service `json:"service"`
+	Keys    []string `json:"keys"`
+}
+
+// matchMany assigns services to a batch of keys, it returns the id of the service of each key,
+// and the keys grouped by service. Duplicated keys are matched only once, the ejected services are skipped.
+func matchMany(o *Outliers, groupName string, keys []string) (map[string]string, []*shard, error) {
+	ring, err := getRing(groupName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	owners := make(map[string]string, len(keys))
+	shards := make([]*shard, 0)
+
+	// Services are indexed by id, so that each payload is unmarshalled only once.
+	index := make(map[string]*shard)
+	for _, key := range keys {
+		if _, ok := owners[key]; ok {
+			continue
+		}
+
+		id, payload, err := o.owner(groupName, ring, key)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s, ok := index[id]
+		if !ok {
+			m := &Member{}
+			if err := m.Unmarshal(payload); err != nil {
+				return nil, nil, err
+			}
+			s = &shard{Service: o.mark(&m.Service), Keys: make([]string, 0)}
+			index[id] = s
+			shards = append(shards, s)
+		}
+
+		s.Keys = append(s.Keys, key)
+		owners[key] = id
+	}
+	return owners, shards, nil
+}
+
+// ListGroups returns the summaries of all the groups, sorted by name.
+func (s *Registry) ListGroups() []*GroupInfo {
+	return listGroups()
+}
+
+// listGroups returns the summaries of all the groups created by Registry.insert, sorted by name.
+func listGroups() []*GroupInfo {
+	groups := make([]*GroupInfo, 0)
+	rings.Range(func(key any, val any) bool {
+		ring := val.(*Ring)
+		groups = append(groups, &GroupInfo{
+			Name:     key.(string),
+			Members:  ring.Len(),
+			Replicas: ring.Replicas(),
+			Updated:  ring.Updated(),
+		})
+		return true
+	})
+
+	sort.Slice(groups, func(i int, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// Registries returns the registry servers of the cluster, sorted by id.
+// The Addr of each one is the address it advertises to the clients for service discovery.
+func (s *Registry) Registries() []*Service {
+	return registries()
+}
+
+// registries returns the services of the registry group, sorted by id.
+func registries() []*Service {
+	services := make([]*Service, 0)
+	ring, err := getRing(RegistryGroup)
+	if err != nil {
+		return services
+	}
+
+	for _, element := range ring.Elements() {
+		m := &Member{}
+		if err := m.Unmarshal(element.Payload); err != nil {
+			log.Printf("[ERROR] element to member err:%s\n", err.Error())
+			continue
+		}
+		services = append(services, &m.Service)
+	}
+	sort.Slice(services, func(i int, j int) bool {
+		return services[i].Id < services[j].Id
+	})
+	return services
+}
+
 // Members returns a list of services for a given group name.
 func (s *Registry) Members(groupName string) []*Service {
 	// Create an empty list of services.
 	services := make([]*Service, 0)
 
-	// Get the group associated with the group name.
-	group, err := chash.GetGroup(groupName)
+	// Get the ring associated with the group name.
+	ring, err := getRing(groupName)
 	if err != nil {
 		return services
 	}
 
 	// Get the elements in the group and create a Service object for each.
-	elements := group.GetElements()
+	elements := ring.Elements()
 	for _, element := range elements {
 		// Unmarshal the payload to create a Member object.
 		m := &Member{}
@@ -176,10 +497,43 @@ func (s *Registry) Members(groupName string) []*Service {
 			continue
 		}
 
-		// Add the Service associated with the Member to the list.
-		services = append(services, &m.Service)
+		// Add the Service associated with the Member to the list, with its ejection state.
+		services = append(services, s.outliers.mark(&m.Service))
 	}
 
 	// Return the list of services.
 	return services
 }
+
+// ReportFailure reports a failed call of a client against a service of a group.
+// The service is ejected once it fails too often, see OptEjection.
+// It returns true if the service is ejected.
+func (s *Registry) ReportFailure(groupName string, id string) (bool, error) {
+	return reportFailure(s.outliers, groupName, id)
+}
+
+// reportFailure records a failure of a service that is a member of the group.
+func reportFailure(o *Outliers, groupName string, id string) (bool, error) {
+	if _, err := findMember(groupName, id); err != nil {
+		return false, err
+	}
+	return o.Report(groupName, id), nil
+}
+
+// findMember returns the member of a service in a group.
+func findMember(groupName string, id string) (*Member, error) {
+	ring, err := getRing(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	element, ok := ring.Element(id)
+	if !ok {
+		return nil, ErrServiceNotFound
+	}
+	m := &Member{}
+	if err := m.Unmarshal(element.Payload); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
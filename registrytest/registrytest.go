@@ -0,0 +1,401 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+// Package registrytest provides an in-memory fake of the registry clients for unit tests,
+// so that the code depending on client.Interface can be tested without starting a registry.
+package registrytest
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/werbenhu/chash"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+)
+
+// The method names of the calls recorded by Fake, the context variants are recorded by the same names.
+const (
+	MethodMatch         = "Match"
+	MethodMatchN        = "MatchN"
+	MethodMatchMany     = "MatchMany"
+	MethodMembers       = "Members"
+	MethodListGroups    = "ListGroups"
+	MethodReportFailure = "ReportFailure"
+)
+
+// Call is a call received by Fake.
+type Call struct {
+	// Method is the name of the method, such as MethodMatch.
+	Method string
+
+	// Group is the group name of the call, it is empty for ListGroups.
+	Group string
+
+	// Keys is the keys of the call, the key of Match and MatchN or the keys of MatchMany.
+	Keys []string
+
+	// Id is the service id of ReportFailure.
+	Id string
+
+	// N is the number of services of MatchN.
+	N int
+}
+
+// group is a group of services on a ring.
+type group struct {
+	ring     *registry.Ring
+	services map[string]*registry.Service
+	updated  time.Time
+}
+
+// Fake is an in-memory registry client that implements client.Interface.
+// The services are placed on a registry.Ring, so the keys are assigned to the services
+// exactly the same as a registry server does for the same services.
+type Fake struct {
+	sync.Mutex
+	groups   map[string]*group
+	replicas map[string]int
+	errs     map[string]error
+	next     map[string][]error
+	calls    []Call
+}
+
+var _ client.Interface = (*Fake)(nil)
+
+// New creates an empty Fake.
+func New() *Fake {
+	return &Fake{
+		groups:   make(map[string]*group),
+		replicas: make(map[string]int),
+		errs:     make(map[string]error),
+		next:     make(map[string][]error),
+		calls:    make([]Call, 0),
+	}
+}
+
+// SetReplicas sets the number of replicated elements of each service of a group, the default is registry.DefaultReplicas.
+// Like a registry server, it only takes effect if it is set before the first service of the group is added.
+func (f *Fake) SetReplicas(groupName string, replicas int) {
+	f.Lock()
+	defer f.Unlock()
+	f.replicas[groupName] = replicas
+}
+
+// AddService adds the services to their groups, or updates the ones whose ids already exist.
+// A group is created with its first service. The services of a group are placed on its ring at once,
+// so adding many services in one call sorts the ring only once.
+func (f *Fake) AddService(services ...*registry.Service) {
+	f.Lock()
+	defer f.Unlock()
+
+	elements := make(map[*group][]*chash.Element)
+	for _, service := range services {
+		g, ok := f.groups[service.Group]
+		if !ok {
+			replicas, ok := f.replicas[service.Group]
+			if !ok {
+				replicas, _ = strconv.Atoi(registry.DefaultReplicas)
+			}
+			g = &group{
+				ring:     registry.NewRing(replicas),
+				services: make(map[string]*registry.Service),
+			}
+			f.groups[service.Group] = g
+		}
+		g.services[service.Id] = service
+		g.updated = time.Now()
+		elements[g] = append(elements[g], &chash.Element{Key: service.Id})
+	}
+	for g, batch := range elements {
+		g.ring.UpsertMany(batch...)
+	}
+}
+
+// RemoveService removes a service from its group, the empty group is kept like a registry server does.
+func (f *Fake) RemoveService(groupName string, id string) {
+	f.Lock()
+	defer f.Unlock()
+
+	if g, ok := f.groups[groupName]; ok {
+		delete(g.services, id)
+		g.ring.Delete(id)
+		g.updated = time.Now()
+	}
+}
+
+// SetEjected ejects or restores a service, like a registry server does when the clients report too many failures.
+// The keys of an ejected service are assigned to the next service on the ring, and Members marks it Ejected.
+// ReportFailure doesn't eject the services by itself.
+func (f *Fake) SetEjected(groupName string, id string, ejected bool) {
+	f.Lock()
+	defer f.Unlock()
+
+	if g, ok := f.groups[groupName]; ok {
+		if service, ok := g.services[id]; ok {
+			// The service is copied, so that the service added by the caller is not modified.
+			copied := *service
+			copied.Ejected = ejected
+			g.services[id] = &copied
+		}
+	}
+}
+
+// SetError makes all the calls of the method fail with err, a nil err clears it.
+func (f *Fake) SetError(method string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	if err == nil {
+		delete(f.errs, method)
+		return
+	}
+	f.errs[method] = err
+}
+
+// FailNext makes the next call of the method fail with err, the errors are queued if it is called more than once.
+// The queued errors are returned before the one set by SetError.
+func (f *Fake) FailNext(method string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.next[method] = append(f.next[method], err)
+}
+
+// Calls returns the calls received so far, in order.
+func (f *Fake) Calls() []Call {
+	f.Lock()
+	defer f.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsOf returns the calls of the method received so far, in order.
+func (f *Fake) CallsOf(method string) []Call {
+	f.Lock()
+	defer f.Unlock()
+	calls := make([]Call, 0)
+	for _, call := range f.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset clears the recorded calls and the scripted errors, the services are kept.
+func (f *Fake) Reset() {
+	f.Lock()
+	defer f.Unlock()
+	f.calls = make([]Call, 0)
+	f.errs = make(map[string]error)
+	f.next = make(map[string][]error)
+}
+
+// call records a call and returns the scripted error of the method, the caller must hold the lock.
+func (f *Fake) call(ctx context.Context, call Call) error {
+	f.calls = append(f.calls, call)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if errs := f.next[call.Method]; len(errs) > 0 {
+		f.next[call.Method] = errs[1:]
+		return errs[0]
+	}
+	return f.errs[call.Method]
+}
+
+// group returns the group, the caller must hold the lock.
+func (f *Fake) group(name string) (*group, error) {
+	g, ok := f.groups[name]
+	if !ok {
+		return nil, registry.ErrGroupNotFound
+	}
+	return g, nil
+}
+
+// owner returns the service that owns the key, skipping the ejected services like a registry server does.
+func (g *group) owner(key string) (*registry.Service, error) {
+	element, err := g.ring.Owner(key, func(id string) bool {
+		return g.services[id].Ejected
+	})
+	if err != nil {
+		return nil, registry.ToErr(err)
+	}
+	return g.services[element.Key], nil
+}
+
+// Match assigns a service to a key using the consistent hashing algorithm.
+func (f *Fake) Match(groupName string, key string) (*registry.Service, error) {
+	return f.MatchContext(context.Background(), groupName, key)
+}
+
+// MatchContext is the same as Match with a context.
+func (f *Fake) MatchContext(ctx context.Context, groupName string, key string) (*registry.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.call(ctx, Call{Method: MethodMatch, Group: groupName, Keys: []string{key}}); err != nil {
+		return nil, err
+	}
+	g, err := f.group(groupName)
+	if err != nil {
+		return nil, err
+	}
+	return g.owner(key)
+}
+
+// MatchN returns up to n distinct services for a key, ordered by their distance to the key on the ring.
+func (f *Fake) MatchN(groupName string, key string, n int) ([]*registry.Service, error) {
+	return f.MatchNContext(context.Background(), groupName, key, n)
+}
+
+// MatchNContext is the same as MatchN with a context.
+func (f *Fake) MatchNContext(ctx context.Context, groupName string, key string, n int) ([]*registry.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.call(ctx, Call{Method: MethodMatchN, Group: groupName, Keys: []string{key}, N: n}); err != nil {
+		return nil, err
+	}
+	g, err := f.group(groupName)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := g.ring.MatchN(key, n)
+	if err != nil {
+		return nil, registry.ToErr(err)
+	}
+	services := make([]*registry.Service, 0, len(elements))
+	for _, element := range elements {
+		services = append(services, g.services[element.Key])
+	}
+	return services, nil
+}
+
+// MatchMany assigns services to a batch of keys.
+func (f *Fake) MatchMany(groupName string, keys []string) (*client.Matches, error) {
+	return f.MatchManyContext(context.Background(), groupName, keys)
+}
+
+// MatchManyContext is the same as MatchMany with a context.
+func (f *Fake) MatchManyContext(ctx context.Context, groupName string, keys []string) (*client.Matches, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.call(ctx, Call{Method: MethodMatchMany, Group: groupName, Keys: keys}); err != nil {
+		return nil, err
+	}
+	g, err := f.group(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := &client.Matches{
+		Owners: make(map[string]*registry.Service, len(keys)),
+		Shards: make([]*client.Shard, 0),
+	}
+	shards := make(map[string]*client.Shard)
+	for _, key := range keys {
+		if _, ok := matches.Owners[key]; ok {
+			continue
+		}
+		service, err := g.owner(key)
+		if err != nil {
+			return nil, err
+		}
+
+		shard, ok := shards[service.Id]
+		if !ok {
+			shard = &client.Shard{Service: service, Keys: make([]string, 0)}
+			shards[service.Id] = shard
+			matches.Shards = append(matches.Shards, shard)
+		}
+		shard.Keys = append(shard.Keys, key)
+		matches.Owners[key] = shard.Service
+	}
+	return matches, nil
+}
+
+// Members returns the list of services in a group, sorted by id.
+func (f *Fake) Members(groupName string) ([]*registry.Service, error) {
+	return f.MembersContext(context.Background(), groupName)
+}
+
+// MembersContext is the same as Members with a context.
+func (f *Fake) MembersContext(ctx context.Context, groupName string) ([]*registry.Service, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	services := make([]*registry.Service, 0)
+	if err := f.call(ctx, Call{Method: MethodMembers, Group: groupName}); err != nil {
+		return services, err
+	}
+	g, err := f.group(groupName)
+	if err != nil {
+		return services, err
+	}
+	for _, service := range g.services {
+		services = append(services, service)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Id < services[j].Id
+	})
+	return services, nil
+}
+
+// ListGroups returns the summaries of all the groups, sorted by name.
+func (f *Fake) ListGroups() ([]*registry.GroupInfo, error) {
+	return f.ListGroupsContext(context.Background())
+}
+
+// ListGroupsContext is the same as ListGroups with a context.
+func (f *Fake) ListGroupsContext(ctx context.Context) ([]*registry.GroupInfo, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.call(ctx, Call{Method: MethodListGroups}); err != nil {
+		return nil, err
+	}
+	groups := make([]*registry.GroupInfo, 0, len(f.groups))
+	for name, g := range f.groups {
+		groups = append(groups, &registry.GroupInfo{
+			Name:     name,
+			Members:  len(g.services),
+			Replicas: g.ring.Replicas(),
+			Updated:  g.updated,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// ReportFailure records the call, it returns whether the service is ejected by SetEjected.
+func (f *Fake) ReportFailure(groupName string, id string) (bool, error) {
+	return f.ReportFailureContext(context.Background(), groupName, id)
+}
+
+// ReportFailureContext is the same as ReportFailure with a context.
+func (f *Fake) ReportFailureContext(ctx context.Context, groupName string, id string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.call(ctx, Call{Method: MethodReportFailure, Group: groupName, Id: id}); err != nil {
+		return false, err
+	}
+	g, err := f.group(groupName)
+	if err != nil {
+		return false, err
+	}
+	service, ok := g.services[id]
+	if !ok {
+		return false, registry.ErrServiceNotFound
+	}
+	return service.Ejected, nil
+}
+
+// Close does nothing, it is there to implement client.Interface.
+func (f *Fake) Close() {}
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+package registry
+
+import (
+	"hash/crc32"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/werbenhu/chash"
+)
+
+// Ring is a consistent hash ring that places and matches keys exactly the same way as a chash group does.
+// Besides the owner of a key, it can walk the ring to find the successors of the owner.
+// The registry keeps the services of each group in a Ring.
+type Ring struct {
+	sync.RWMutex
+	replicas int                       // The number of virtual nodes of each element.
+	circle   chash.Circle              // The sorted hashes of the virtual nodes.
+	rows     map[uint32]*chash.Element // The element of each virtual node.
+	elements map[string]*chash.Element // The elements indexed by key.
+	updated  time.Time                 // The last time an element was upserted or deleted.
+}
+
+// NewRing creates a new ring, replicas is the number of virtual nodes of each element.
+func NewRing(replicas int) *Ring {
+	return &Ring{
+		replicas: replicas,
+		circle:   make(chash.Circle, 0),
+		rows:     make(map[uint32]*chash.Element),
+		elements: make(map[string]*chash.Element),
+		updated:  time.Now(),
+	}
+}
+
+// hash returns the position of a key on the ring, the same as chash.
+func (r *Ring) hash(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// virtualKey returns the key of the idx-th virtual node of an element, the same as chash.
+func (r *Ring) virtualKey(key string, idx int) string {
+	return strconv.Itoa(idx) + key
+}
+
+// Replicas returns the number of virtual nodes of each element.
+func (r *Ring) Replicas() int {
+	return r.replicas
+}
+
+// Updated returns the last time an element was upserted or deleted.
+func (r *Ring) Updated() time.Time {
+	r.RLock()
+	defer r.RUnlock()
+	return r.updated
+}
+
+// Upsert inserts an element into the ring, or replaces it if the key already exists.
+func (r *Ring) Upsert(key string, payload []byte) {
+	r.UpsertMany(&chash.Element{Key: key, Payload: payload})
+}
+
+// UpsertMany inserts a batch of elements into the ring, or replaces those whose keys already exist.
+// The ring is sorted once for the whole batch.
+func (r *Ring) UpsertMany(elements ...*chash.Element) {
+	r.Lock()
+	defer r.Unlock()
+
+	// The existing elements are removed while the circle is still sorted, since the removal searches the circle.
+	// A key repeated in elements is placed once, with its last element.
+	latest := make(map[string]*chash.Element, len(elements))
+	for _, element := range elements {
+		if _, ok := r.elements[element.Key]; ok {
+			r.delete(element.Key)
+		}
+		latest[element.Key] = element
+	}
+
+	for _, element := range elements {
+		if latest[element.Key] != element {
+			continue
+		}
+		delete(latest, element.Key)
+		r.elements[element.Key] = element
+		for i := 0; i < r.replicas; i++ {
+			crc := r.hash(r.virtualKey(element.Key, i))
+			r.rows[crc] = element
+			r.circle = append(r.circle, crc)
+		}
+	}
+	r.circle.Sort()
+	r.updated = time.Now()
+}
+
+// Delete removes an element from the ring.
+func (r *Ring) Delete(key string) {
+	r.Lock()
+	defer r.Unlock()
+	r.delete(key)
+	r.updated = time.Now()
+}
+
+// delete removes an element from the ring, the caller must hold the lock.
+func (r *Ring) delete(key string) {
+	delete(r.elements, key)
+	for i := 0; i < r.replicas; i++ {
+		crc := r.hash(r.virtualKey(key, i))
+		delete(r.rows, crc)
+		if idx, ok := r.circle.Search(crc); ok {
+			r.circle = append(r.circle[:idx], r.circle[idx+1:]...)
+		}
+	}
+}
+
+// Len returns the number of elements in the ring.
+func (r *Ring) Len() int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.elements)
+}
+
+// Element returns the element of a key.
+func (r *Ring) Element(key string) (*chash.Element, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	element, ok := r.elements[key]
+	return element, ok
+}
+
+// Elements returns all the elements in the ring.
+func (r *Ring) Elements() []*chash.Element {
+	r.RLock()
+	defer r.RUnlock()
+
+	elements := make([]*chash.Element, 0, len(r.elements))
+	for _, e := range r.elements {
+		elements = append(elements, e)
+	}
+	return elements
+}
+
+// Match returns the key and payload of the element that owns the key.
+func (r *Ring) Match(key string) (string, []byte, error) {
+	elements, err := r.MatchN(key, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	return elements[0].Key, elements[0].Payload, nil
+}
+
+// Owner returns the element that owns the key, skipping the elements for which skip returns true.
+// The key is assigned to the next element on the ring that is not skipped, the same as the skipped ones had left,
+// or to its owner if all the elements are skipped. skip is called with the ring locked, it must not change the ring.
+func (r *Ring) Owner(key string, skip func(id string) bool) (*chash.Element, error) {
+	var owner, found *chash.Element
+	err := r.Walk(key, func(element *chash.Element) bool {
+		if owner == nil {
+			owner = element
+		}
+		if skip(element.Key) {
+			return true
+		}
+		found = element
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return owner, nil
+	}
+	return found, nil
+}
+
+// MatchN returns up to n distinct elements for the key, ordered by their distance to the key on the ring.
+// The first one is the owner of the key, the next one is the element that would own the key if the first one left, and so on.
+// If there are fewer than n elements in the ring, all of them are returned.
+func (r *Ring) MatchN(key string, n int) ([]*chash.Element, error) {
+	if n <= 0 {
+		return nil, ErrMatchCount
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	// n comes from the clients, the elements are allocated for no more than the ring has.
+	if n > len(r.elements) {
+		n = len(r.elements)
+	}
+	elements := make([]*chash.Element, 0, n)
+	err := r.walk(key, func(element *chash.Element) bool {
+		elements = append(elements, element)
+		return len(elements) < n
+	})
+	if err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// Walk calls fn on the distinct elements for the key in the order of MatchN, until fn returns false
+// or all the elements are visited. The ring must not be changed by fn.
+func (r *Ring) Walk(key string, fn func(element *chash.Element) bool) error {
+	r.RLock()
+	defer r.RUnlock()
+	return r.walk(key, fn)
+}
+
+// walk is Walk without the lock, the caller must hold the read lock.
+func (r *Ring) walk(key string, fn func(element *chash.Element) bool) error {
+	point, ok := r.circle.Match(r.hash(key))
+	if !ok || len(r.elements) == 0 {
+		return chash.ErrNoResultMatched
+	}
+
+	// Walk the ring from the matched point in the same direction as chash falls back when an element is removed.
+	// Only the elements visited are tracked, so that a walk that stops at the owner costs little.
+	var found map[string]struct{}
+	length := len(r.circle)
+	for i := 0; i < length; i++ {
+		element, ok := r.rows[r.circle[(point-i+length)%length]]
+		if !ok {
+			continue
+		}
+		if _, ok := found[element.Key]; ok {
+			continue
+		}
+		if !fn(element) {
+			return nil
+		}
+		if found == nil {
+			found = make(map[string]struct{})
+		}
+		found[element.Key] = struct{}{}
+		if len(found) == len(r.elements) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -2,33 +2,100 @@ package registry
 
 import (
 	"context"
+	"io"
 	"log"
 	"net"
+	"sync"
+	"time"
 
-	"github.com/werbenhu/chash"
+	"github.com/rs/xid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
 // RpcServer is a gRPC server for service discovery
 type RpcServer struct {
-	addr string
-	rpc  *grpc.Server
+	addr     string
+	opt      *Option
+	rpc      *grpc.Server   // the gRPC server, guarded by mu
+	stopped  bool           // whether Stop is called, so that Start does not serve after that, guarded by mu
+	mu       sync.Mutex     // Start and Stop may run on different goroutines
+	health   *health.Server // the standard gRPC health service, nil if disabled
+	watchers *Watchers      // the subscribers of membership changes
+	outliers *Outliers      // the services ejected for the failures reported by the clients
+	leases   *Leases        // the services registered by the Register streams, nil if the registration is disabled
 }
 
-// NewRpcServer creates a new RpcServer object
+// NewRpcServer creates a new RpcServer object with the default options.
+// The standalone server reports serving by its health service, SetServing changes it.
 func NewRpcServer() *RpcServer {
-	return &RpcServer{}
+	opt := DefaultOption()
+	s := newRpcServer(opt, NewOutliers(opt.EjectThreshold, opt.EjectWindow, opt.EjectCooldown, opt.EjectMaxPercent, nil), nil)
+	s.SetServing(true)
+	return s
 }
 
-// Match assigns a service to a key using the consistent hashing algorithm
+// newRpcServer creates a new RpcServer object with the options, the outliers and the registrations of the registry
+func newRpcServer(opt *Option, outliers *Outliers, leases *Leases) *RpcServer {
+	s := &RpcServer{
+		opt:      opt,
+		watchers: NewWatchers(),
+		outliers: outliers,
+		leases:   leases,
+	}
+
+	// The registry is not serving until it has joined the serf cluster, see Registry.onLocalMember.
+	if opt.Health {
+		s.health = health.NewServer()
+		s.SetServing(false)
+	}
+	return s
+}
+
+// SetServing sets the status reported by the gRPC health service, both for the server and the R service
+func (s *RpcServer) SetServing(serving bool) {
+	if s.health == nil {
+		return
+	}
+
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(_R_serviceDesc.ServiceName, status)
+}
+
+// OnMemberJoin notifies the watchers that a new service is registered
+func (s *RpcServer) OnMemberJoin(m *Member) error {
+	return s.watchers.OnMemberJoin(m)
+}
+
+// OnMemberLeave notifies the watchers that a service left
+func (s *RpcServer) OnMemberLeave(m *Member) error {
+	return s.watchers.OnMemberLeave(m)
+}
+
+// OnMemberUpdate notifies the watchers that a service is updated
+func (s *RpcServer) OnMemberUpdate(m *Member) error {
+	return s.watchers.OnMemberUpdate(m)
+}
+
+// Match assigns a service to a key using the consistent hashing algorithm,
+// the key is assigned to the next service on the ring if its service is ejected
 func (s *RpcServer) Match(ctx context.Context, req *MatchRequest) (*MatchResponse, error) {
 
-	group, err := chash.GetGroup(req.Group)
+	ring, err := getRing(req.Group)
 	if err != nil {
 		return nil, err
 	}
 
-	_, payload, err := group.Match(req.Key)
+	_, payload, err := s.outliers.owner(req.Group, ring, req.Key)
 	if err != nil {
 		return nil, err
 	}
@@ -38,39 +105,290 @@ func (s *RpcServer) Match(ctx context.Context, req *MatchRequest) (*MatchRespons
 		return nil, err
 	}
 
-	return &MatchResponse{
-		Id:    m.Service.Id,
-		Group: m.Service.Group,
-		Addr:  m.Service.Addr,
-	}, nil
+	return newMatchResponse(s.outliers.mark(&m.Service)), nil
 }
 
 // Members returns a list of services in a group
 func (s *RpcServer) Members(ctx context.Context, req *MembersRequest) (*MembersResponse, error) {
-	group, err := chash.GetGroup(req.Group)
+	ring, err := getRing(req.Group)
 	if err != nil {
 		return nil, err
 	}
 
-	elements := group.GetElements()
+	elements := ring.Elements()
 	services := make([]*MatchResponse, 0)
 	for _, element := range elements {
 		m := &Member{}
 		if err := m.Unmarshal(element.Payload); err == nil {
-			service := &MatchResponse{
-				Id:    m.Service.Id,
-				Group: m.Service.Group,
-				Addr:  m.Service.Addr,
-			}
-			services = append(services, service)
+			services = append(services, newMatchResponse(s.outliers.mark(&m.Service)))
 		}
 	}
 
 	return &MembersResponse{
 		Services: services,
+		Replicas: int32(ring.Replicas()),
 	}, nil
 }
 
+// MatchN returns up to n distinct services for a key, ordered by their distance to the key on the ring
+func (s *RpcServer) MatchN(ctx context.Context, req *MatchNRequest) (*MatchNResponse, error) {
+	services, err := matchN(s.outliers, req.Group, req.Key, int(req.N))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &MatchNResponse{
+		Services: make([]*MatchResponse, 0, len(services)),
+	}
+	for _, service := range services {
+		resp.Services = append(resp.Services, newMatchResponse(service))
+	}
+	return resp, nil
+}
+
+// MatchMany assigns services to a batch of keys using the consistent hashing algorithm.
+// The response maps each key to the id of its service, and groups the keys by service.
+func (s *RpcServer) MatchMany(ctx context.Context, req *MatchManyRequest) (*MatchManyResponse, error) {
+	owners, shards, err := matchMany(s.outliers, req.Group, req.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &MatchManyResponse{
+		Owners:   owners,
+		Services: make([]*ServiceKeys, 0, len(shards)),
+	}
+	for _, shard := range shards {
+		resp.Services = append(resp.Services, &ServiceKeys{
+			Service: newMatchResponse(shard.Service),
+			Keys:    shard.Keys,
+		})
+	}
+	return resp, nil
+}
+
+// ListGroups returns the summaries of all the groups, the updated time is in unix milliseconds
+func (s *RpcServer) ListGroups(ctx context.Context, req *ListGroupsRequest) (*ListGroupsResponse, error) {
+	groups := listGroups()
+	resp := &ListGroupsResponse{
+		Groups: make([]*GroupResponse, 0, len(groups)),
+	}
+	for _, group := range groups {
+		resp.Groups = append(resp.Groups, &GroupResponse{
+			Name:     group.Name,
+			Members:  int32(group.Members),
+			Replicas: int32(group.Replicas),
+			Updated:  group.Updated.UnixMilli(),
+		})
+	}
+	return resp, nil
+}
+
+// Watch sends the current services of a group first, and then the join/leave/update changes of the group
+func (s *RpcServer) Watch(req *WatchRequest, stream R_WatchServer) error {
+	// Subscribe before taking the snapshot so that no change is missed in between.
+	events := s.watchers.Subscribe(req.Group)
+	defer s.watchers.Unsubscribe(req.Group, events)
+
+	snapshot := &WatchResponse{
+		Type:     EventSnapshot,
+		Services: make([]*MatchResponse, 0),
+	}
+
+	// A group that does not exist yet is watched with an empty snapshot.
+	if ring, err := getRing(req.Group); err == nil {
+		snapshot.Replicas = int32(ring.Replicas())
+		for _, element := range ring.Elements() {
+			m := &Member{}
+			if err := m.Unmarshal(element.Payload); err == nil {
+				snapshot.Services = append(snapshot.Services, newMatchResponse(s.outliers.mark(&m.Service)))
+			}
+		}
+	}
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case e, ok := <-events:
+			if !ok {
+				return ErrWatcherClosed
+			}
+
+			resp := &WatchResponse{
+				Type:     e.Type,
+				Services: make([]*MatchResponse, 0, len(e.Services)),
+			}
+			if ring, err := getRing(req.Group); err == nil {
+				resp.Replicas = int32(ring.Replicas())
+			}
+			for _, service := range e.Services {
+				// The services of the event are shared by the watchers, the ejection state is set on the response.
+				r := newMatchResponse(service)
+				r.Ejected = s.outliers.Ejected(service.Group, service.Id)
+				resp.Services = append(resp.Services, r)
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ReportFailure records a failed call of a client against a service, the service is ejected once it fails too often
+func (s *RpcServer) ReportFailure(ctx context.Context, req *ReportFailureRequest) (*ReportFailureResponse, error) {
+	ejected, err := reportFailure(s.outliers, req.Group, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &ReportFailureResponse{Ejected: ejected}, nil
+}
+
+// Registries returns the registry servers of the cluster, so that the clients can find all of them from one of them
+func (s *RpcServer) Registries(ctx context.Context, req *RegistriesRequest) (*RegistriesResponse, error) {
+	services := registries()
+	resp := &RegistriesResponse{
+		Registries: make([]*MatchResponse, 0, len(services)),
+	}
+	for _, service := range services {
+		resp.Registries = append(resp.Registries, newMatchResponse(service))
+	}
+	return resp, nil
+}
+
+// Register keeps a service registered while the stream is alive, for the services that can't join the serf cluster.
+// The first message sets the service, a random id is generated if it is empty. The later messages update it,
+// their meta replaces the meta of the service, and their empty group and addr keep the previous ones.
+// Each message is answered with the registered service. If the stream breaks, the service is removed
+// after the grace period, see OptRegisterGrace. If the client closes the stream, it is removed at once.
+func (s *RpcServer) Register(stream R_RegisterServer) error {
+	if s.leases == nil {
+		return status.Error(codes.Unimplemented, "the registration is served by the registry servers only")
+	}
+
+	// The messages are received in another goroutine, so that the registration is renewed meanwhile.
+	reqs := make(chan *RegisterRequest)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case reqs <- req:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	grace := s.opt.RegisterGrace
+	if grace <= 0 {
+		grace = registerGrace
+	}
+	renew := time.NewTicker(grace / 3)
+	defer renew.Stop()
+
+	var m *Member
+	for {
+		select {
+		case req := <-reqs:
+			m = registerMember(m, req.Service)
+			if err := s.leases.Register(m, grace); err != nil {
+				return err
+			}
+			resp := &RegisterResponse{
+				Service: newMatchResponse(&m.Service),
+				Grace:   grace.Milliseconds(),
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		case <-renew.C:
+			if m == nil {
+				continue
+			}
+			// The renewals are kept by the local registry server, see Leases.Renew.
+			// The service is deregistered by another api meanwhile.
+			if _, _, err := s.leases.Renew(m.Service.Id); err != nil {
+				return err
+			}
+		case err := <-errs:
+			// The stream is broken, the registration expires after the grace period.
+			if err != io.EOF {
+				return err
+			}
+			if m != nil {
+				if err := s.leases.Deregister(m.Service.Id); err != nil && err != ErrServiceNotFound {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// registerMember returns the member of a service registered by the Register stream, updated by the service.
+// The meta of the service can't set the tags reserved by the registry.
+func registerMember(prev *Member, service *MatchResponse) *Member {
+	if service == nil {
+		service = &MatchResponse{}
+	}
+	if prev == nil {
+		id := service.Id
+		if len(id) == 0 {
+			id = xid.New().String()
+		}
+		prev = NewMember(id, "", "", "", service.Group, service.Addr)
+	}
+
+	m := NewMember(prev.Id, "", "", "", prev.Service.Group, prev.Service.Addr)
+	m.Replicas = prev.Replicas
+	if len(service.Group) > 0 {
+		m.Service.Group = service.Group
+	}
+	if len(service.Addr) > 0 {
+		m.Service.Addr = service.Addr
+	}
+	// The tags reserved by the registry are ignored, they would move the service or change its virtual nodes.
+	for k, v := range service.Meta {
+		if !IsReservedTag(k) && k != TagHttp {
+			m.SetTag(k, v)
+		}
+	}
+	return m
+}
+
+// newMatchResponse converts a service to the gRPC response object
+func newMatchResponse(service *Service) *MatchResponse {
+	return &MatchResponse{
+		Id:      service.Id,
+		Group:   service.Group,
+		Addr:    service.Addr,
+		Meta:    service.Meta,
+		Ejected: service.Ejected,
+	}
+}
+
+// unaryErrorInterceptor converts the errors returned by the unary handlers to gRPC status errors
+func unaryErrorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, ToStatusError(err)
+	}
+	return resp, nil
+}
+
+// streamErrorInterceptor converts the errors returned by the stream handlers to gRPC status errors
+func streamErrorInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return ToStatusError(handler(srv, ss))
+}
+
 // Start starts the gRPC server
 func (s *RpcServer) Start(addr string) error {
 	var err error
@@ -81,15 +399,57 @@ func (s *RpcServer) Start(addr string) error {
 		return err
 	}
 
-	s.rpc = grpc.NewServer()
-	RegisterRServer(s.rpc, s)
-	return s.rpc.Serve(listener)
+	// The errors are converted to status errors before any other interceptor returns.
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryErrorInterceptor),
+		grpc.ChainStreamInterceptor(streamErrorInterceptor),
+	}
+	if len(s.opt.CertFile) > 0 {
+		cfg, err := NewServerTLSConfig(s.opt.CertFile, s.opt.KeyFile, s.opt.ClientCAFile)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+	if s.opt.Authenticator != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(UnaryAuthInterceptor(s.opt.Authenticator)),
+			grpc.ChainStreamInterceptor(StreamAuthInterceptor(s.opt.Authenticator)),
+		)
+	}
+
+	rpc := grpc.NewServer(opts...)
+	RegisterRServer(rpc, s)
+	if s.health != nil {
+		healthpb.RegisterHealthServer(rpc, s.health)
+	}
+	if s.opt.Reflection {
+		reflection.Register(rpc)
+	}
+
+	// Stop may have been called while listening, the server is not started then.
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		listener.Close()
+		return grpc.ErrServerStopped
+	}
+	s.rpc = rpc
+	s.mu.Unlock()
+	return rpc.Serve(listener)
 }
 
-// Stop stops the gRPC server
+// Stop stops the gRPC server, the server is not started if Start is called afterwards
 func (s *RpcServer) Stop() {
-	if s.rpc != nil {
-		s.rpc.Stop()
+	s.SetServing(false)
+	s.watchers.Close()
+	s.mu.Lock()
+	s.stopped = true
+	rpc := s.rpc
+	s.mu.Unlock()
+	if rpc != nil {
+		rpc.Stop()
 		log.Printf("[DEBUG] Rpc server is stoped.\n")
 	}
 }
@@ -24,6 +24,12 @@ const (
 
 	// TagReplicas is the tag key of replicas.
 	TagReplicas = "replicas"
+
+	// TagHttp is the tag key of the advertised address of a registry server's http api.
+	TagHttp = "http"
+
+	// userEventSizeLimit is the maximum size of the name and payload of a user event, the largest one serf allows.
+	userEventSizeLimit = 9 * 1024
 )
 
 // Serf represents a discovery instance of hashicorp/serf.
@@ -110,6 +116,7 @@ func (s *Serf) Start() error {
 	cfg.MemberlistConfig.BindAddr = host
 	cfg.MemberlistConfig.BindPort = port
 	cfg.EventCh = s.events
+	cfg.UserEventSizeLimit = userEventSizeLimit
 
 	// Set up the logger for Serf and the memberlist package.
 	filter := &logutils.LevelFilter{
@@ -158,6 +165,18 @@ func (s *Serf) Join(members []string) error {
 	return err
 }
 
+// UserEvent broadcasts a custom event to all the members of the Serf cluster.
+// The event is not coalesced, so that every event is received by the handlers.
+func (s *Serf) UserEvent(name string, payload []byte) error {
+	return s.serf.UserEvent(name, payload, false)
+}
+
+// EventTime returns the Lamport time of the user events, it is synchronized with the cluster once joined.
+func (s *Serf) EventTime() uint64 {
+	t, _ := strconv.ParseUint(s.serf.Stats()["event_time"], 10, 64)
+	return t
+}
+
 // splitHostPort splits an address of the form "host:port" into separate host and port strings.
 func (s *Serf) splitHostPort(addr string) (string, int, error) {
 	h, p, err := net.SplitHostPort(addr)
@@ -229,6 +248,15 @@ func (s *Serf) loop() {
 					}
 				}
 			}
+
+		// handle custom event if the handler receives them
+		case serf.EventUser:
+			event := e.(serf.UserEvent)
+			if h, ok := s.handler.(UserEventHandler); ok {
+				if err := h.OnUserEvent(event.Name, uint64(event.LTime), event.Payload); err != nil {
+					log.Printf("[ERROR] serf handle user event %s err:%s\n", event.Name, err.Error())
+				}
+			}
 		}
 	}
 }
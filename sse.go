@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sseHistory is the number of the latest events kept to resume the SSE watch streams.
+	sseHistory = 1024
+
+	// sseHeartbeat is the default interval of the heartbeats of the SSE watch streams.
+	sseHeartbeat = 15 * time.Second
+)
+
+// OnMemberJoin publishes a join event to the SSE watch streams
+func (h *Http) OnMemberJoin(m *Member) error {
+	h.publish(EventJoin, m)
+	return nil
+}
+
+// OnMemberLeave publishes a leave event to the SSE watch streams
+func (h *Http) OnMemberLeave(m *Member) error {
+	h.publish(EventLeave, m)
+	return nil
+}
+
+// OnMemberUpdate publishes an update event to the SSE watch streams
+func (h *Http) OnMemberUpdate(m *Member) error {
+	h.publish(EventUpdate, m)
+	return nil
+}
+
+// publish numbers an event of the member, keeps it in the history and sends it to the watchers.
+func (h *Http) publish(typ string, m *Member) {
+	service := m.Service
+	h.outliers.mark(&service)
+	e := &Event{
+		Type:     typ,
+		Group:    service.Group,
+		Services: []*Service{&service},
+	}
+	if ring, err := getRing(service.Group); err == nil {
+		e.Replicas = ring.Replicas()
+	}
+
+	// The lock is held while publishing, so that the watchers receive the events in the order of their numbers.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	e.seq = h.seq
+	h.history = append(h.history, e)
+	if len(h.history) > sseHistory {
+		h.history = append(make([]*Event, 0, sseHistory), h.history[len(h.history)-sseHistory:]...)
+	}
+	h.watchers.Publish(e)
+}
+
+// since returns the events of the group after the event id, and the number of the last event.
+// It returns false if the stream can't be resumed from the id, such as an id of another server
+// or an event that is no longer in the history.
+func (h *Http) since(group string, id string) ([]*Event, uint64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != h.epoch {
+		return nil, h.seq, false
+	}
+	last, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || last > h.seq {
+		return nil, h.seq, false
+	}
+	if last < h.seq && (len(h.history) == 0 || h.history[0].seq > last+1) {
+		return nil, h.seq, false
+	}
+
+	events := make([]*Event, 0)
+	for _, e := range h.history {
+		if e.seq > last && e.Group == group {
+			events = append(events, e)
+		}
+	}
+	return events, h.seq, true
+}
+
+// snapshot returns a snapshot event of all the current services of the group, numbered with the last event.
+// The changes numbered up to the snapshot are reflected in it, since the rings are changed before publishing.
+func (h *Http) snapshot(group string) *Event {
+	h.mu.Lock()
+	seq := h.seq
+	h.mu.Unlock()
+
+	e := &Event{
+		Type:     EventSnapshot,
+		Group:    group,
+		Services: make([]*Service, 0),
+		seq:      seq,
+	}
+	// A group that does not exist yet is watched with an empty snapshot.
+	if ring, err := getRing(group); err == nil {
+		e.Replicas = ring.Replicas()
+		for _, element := range ring.Elements() {
+			m := &Member{}
+			if err := m.Unmarshal(element.Payload); err == nil {
+				e.Services = append(e.Services, h.outliers.mark(&m.Service))
+			}
+		}
+	}
+	return e
+}
+
+// send writes an event to the SSE stream, the id of the event is the id of the server and the number of the event.
+func (h *Http) send(c *gin.Context, e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "id: %s-%d\nevent: %s\ndata: %s\n\n", h.epoch, e.seq, e.Type, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// watch streams the membership changes of a group as Server-Sent Events.
+// The first event is a snapshot of the group, followed by the join, leave and update events.
+// If the Last-Event-ID header (or the lastEventId parameter) is set by a reconnecting client,
+// the missed events are sent instead of the snapshot if they are still known.
+func (h *Http) watch(c *gin.Context) {
+	name := c.Query("group")
+	lastId := c.GetHeader("Last-Event-ID")
+	if len(lastId) == 0 {
+		lastId = c.Query("lastEventId")
+	}
+
+	// Subscribe before taking the snapshot so that no change is missed in between.
+	events := h.watchers.Subscribe(name)
+	defer h.watchers.Unsubscribe(name, events)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	missed, cursor, ok := h.since(name, lastId)
+	if !ok {
+		snapshot := h.snapshot(name)
+		missed, cursor = []*Event{snapshot}, snapshot.seq
+	}
+	for _, e := range missed {
+		if err := h.send(c, e); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	interval := h.opt.SSEHeartbeat
+	if interval <= 0 {
+		interval = sseHeartbeat
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			// A comment line keeps the stream alive without raising an event in the browsers.
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case e, ok := <-events:
+			// The stream is closed if the client is too slow, it reconnects and resumes from its last event.
+			if !ok {
+				return
+			}
+			if e.seq <= cursor {
+				continue
+			}
+			if err := h.send(c, e); err != nil {
+				return
+			}
+		}
+	}
+}
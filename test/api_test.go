@@ -0,0 +1,128 @@
+package test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_RegistryServeRpcAndHttp(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHttp("127.0.0.1:9002", ""),
+	})
+	served := make(chan struct{})
+	go func() {
+		r.Serve()
+		close(served)
+	}()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member))
+
+	// Both apis serve the same services.
+	rc, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+	hc, err := client.NewHttpClient("127.0.0.1:9002")
+	assert.Nil(t, err)
+
+	service, err := rc.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+	service, err = hc.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+
+	// The address of the http api is advertised with the registry server.
+	registries, err := rc.Registries()
+	assert.Nil(t, err)
+	assert.Len(t, registries, 1)
+	assert.Equal(t, "127.0.0.1:9000", registries[0].Addr)
+	assert.Equal(t, "127.0.0.1:9002", registries[0].Meta[registry.TagHttp])
+
+	// Close stops all the apis.
+	hc.Close()
+	rc.Close()
+	r.Close()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("Serve didn't return after Close")
+	}
+}
+
+func Test_RegistryServeHttpOnly(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptRpc(false),
+		registry.OptHttp("127.0.0.1:9002", "10.0.0.1:9002"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	hc, err := client.NewHttpClient("127.0.0.1:9002")
+	assert.Nil(t, err)
+	registries, err := hc.Registries()
+	assert.Nil(t, err)
+	assert.Len(t, registries, 1)
+	assert.Equal(t, "", registries[0].Addr)
+	assert.Equal(t, "10.0.0.1:9002", registries[0].Meta[registry.TagHttp])
+
+	// The gRPC api is not served.
+	rc, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+	_, err = rc.ListGroups()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	rc.Close()
+	hc.Close()
+	r.Close()
+}
+
+func Test_RegistryServeNoApi(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptRpc(false),
+	})
+	assert.PanicsWithValue(t, registry.ErrNoApiEnabled, func() {
+		r.Serve()
+	})
+	r.Close()
+}
+
+func Test_ApiStopBeforeStart(t *testing.T) {
+	// An api stopped before it starts serving does not serve, and releases its address.
+	h := registry.NewHttp()
+	h.Stop()
+	assert.Equal(t, http.ErrServerClosed, h.Start("127.0.0.1:9002"))
+
+	s := registry.NewRpcServer()
+	s.Stop()
+	assert.Equal(t, grpc.ErrServerStopped, s.Start("127.0.0.1:9002"))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:9002")
+	assert.Nil(t, err)
+	listener.Close()
+}
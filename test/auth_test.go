@@ -0,0 +1,187 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func Test_TokenAuthenticator(t *testing.T) {
+	auth := registry.NewTokenAuthenticator("token1", "token2")
+	assert.Nil(t, auth.Authenticate("token1"))
+	assert.Nil(t, auth.Authenticate("token2"))
+	assert.Equal(t, registry.ErrTokenInvalid, auth.Authenticate("token3"))
+	assert.Equal(t, registry.ErrTokenEmpty, auth.Authenticate(""))
+}
+
+func Test_HmacAuthenticator(t *testing.T) {
+	auth := registry.NewHmacAuthenticator([]byte("secret"))
+
+	token := auth.Sign("webservice:1", time.Minute)
+	assert.Nil(t, auth.Authenticate(token))
+
+	expired := auth.Sign("webservice", -time.Minute)
+	assert.Equal(t, registry.ErrTokenExpired, auth.Authenticate(expired))
+
+	other := registry.NewHmacAuthenticator([]byte("other"))
+	assert.Equal(t, registry.ErrTokenInvalid, other.Authenticate(token))
+
+	assert.Equal(t, registry.ErrTokenInvalid, auth.Authenticate("xxx"))
+	assert.Equal(t, registry.ErrTokenInvalid, auth.Authenticate(token+"x"))
+	assert.Equal(t, registry.ErrTokenEmpty, auth.Authenticate(""))
+}
+
+func Test_BearerToken(t *testing.T) {
+	assert.Equal(t, "xxx", registry.BearerToken("Bearer xxx"))
+	assert.Equal(t, "xxx", registry.BearerToken("bearer xxx"))
+	assert.Equal(t, "", registry.BearerToken("Basic xxx"))
+	assert.Equal(t, "", registry.BearerToken("xxx"))
+}
+
+func Test_AuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(registry.AuthMiddleware(registry.NewTokenAuthenticator("token")))
+	r.GET("/members", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"code": 0})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/members", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/members", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The errors of a custom authenticator are answered as ErrTokenInvalid.
+	r = gin.New()
+	r.Use(registry.AuthMiddleware(authenticatorFunc(func(token string) error {
+		return errors.New("unknown user")
+	})))
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/members", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	body := &struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(body))
+	assert.Equal(t, registry.ErrTokenInvalid.Code, body.Code)
+	assert.Equal(t, registry.ErrTokenInvalid.Msg, body.Msg)
+}
+
+// authenticatorFunc is a custom authenticator that returns its own errors.
+type authenticatorFunc func(token string) error
+
+func (f authenticatorFunc) Authenticate(token string) error {
+	return f(token)
+}
+
+func Test_RpcClientAuth(t *testing.T) {
+	secret := []byte("secret")
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptAuthenticator(registry.NewHmacAuthenticator(secret)),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	err := r.OnMemberJoin(member)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000", client.OptHmacToken(secret, "test", time.Minute))
+	assert.Nil(t, err)
+	service, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+
+	// Streams are authenticated as well.
+	w, err := c.Watch(serviceGroup)
+	assert.Nil(t, err)
+	e, ok := <-w.Events()
+	assert.True(t, ok)
+	assert.Equal(t, registry.EventSnapshot, e.Type)
+	w.Stop()
+	c.Close()
+
+	c, err = client.NewRpcClient("127.0.0.1:9000", client.OptToken("wrong"))
+	assert.Nil(t, err)
+	_, err = c.Match(serviceGroup, "werben")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	w, err = c.Watch(serviceGroup)
+	assert.Nil(t, err)
+	_, ok = <-w.Events()
+	assert.False(t, ok)
+	assert.Equal(t, codes.Unauthenticated, status.Code(w.Err()))
+	c.Close()
+
+	// The health service can be called without a token.
+	conn, err := grpc.Dial("127.0.0.1:9000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	conn.Close()
+
+	r.Close()
+}
+
+func Test_ClientCustomAuth(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHttp("127.0.0.1:9002", ""),
+		registry.OptAuthenticator(authenticatorFunc(func(token string) error {
+			return errors.New("unknown user")
+		})),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	// Both clients see the errors of a custom authenticator as ErrTokenInvalid.
+	rc, err := client.NewRpcClient("127.0.0.1:9000", client.OptToken("token"))
+	assert.Nil(t, err)
+	_, err = rc.Match("testgroup", "werben")
+	assert.True(t, errors.Is(err, registry.ErrTokenInvalid))
+	rc.Close()
+
+	hc, err := client.NewHttpClient("127.0.0.1:9002", client.OptToken("token"))
+	assert.Nil(t, err)
+	_, err = hc.Match("testgroup", "werben")
+	assert.True(t, errors.Is(err, registry.ErrTokenInvalid))
+	hc.Close()
+
+	r.Close()
+}
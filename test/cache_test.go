@@ -0,0 +1,220 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+)
+
+func Test_RpcClientCache(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+
+	remote, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+	cached, err := client.NewRpcClient("127.0.0.1:9000", client.OptCache(true))
+	assert.Nil(t, err)
+
+	_, ok := cached.Staleness(serviceGroup)
+	assert.False(t, ok)
+
+	// The cached rings match the keys exactly the same as the registry.
+	same := func() {
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%d", i)
+			expected, err := remote.Match(serviceGroup, key)
+			assert.Nil(t, err)
+			service, err := cached.Match(serviceGroup, key)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, service)
+
+			expectedN, err := remote.MatchN(serviceGroup, key, 2)
+			assert.Nil(t, err)
+			services, err := cached.MatchN(serviceGroup, key, 2)
+			assert.Nil(t, err)
+			assert.Equal(t, expectedN, services)
+		}
+	}
+	same()
+	time.Sleep(sleepTime)
+	staleness, ok := cached.Staleness(serviceGroup)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), staleness)
+
+	// The changes are applied to the cached rings.
+	member3 := registry.NewMember("testid3", "127.0.0.1:8372", "127.0.0.1:8372", "127.0.0.1:7370", serviceGroup, "127.0.0.1:82")
+	assert.Nil(t, r.OnMemberJoin(member3))
+	assert.Nil(t, r.OnMemberLeave(member1))
+	time.Sleep(sleepTime)
+	same()
+
+	_, err = cached.Match("othergroup", "werben")
+	assert.True(t, errors.Is(err, registry.ErrGroupNotFound))
+
+	// The last known ring is used and reported stale when the registry is unreachable.
+	expected, err := cached.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	remote.Close()
+	r.Close()
+	time.Sleep(sleepTime)
+
+	service, err := cached.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, service)
+	staleness, ok = cached.Staleness(serviceGroup)
+	assert.True(t, ok)
+	assert.Greater(t, staleness, time.Duration(0))
+
+	cached.Close()
+}
+
+func Test_RpcClientSnapshotFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	newRegistry := func() *registry.Registry {
+		r := registry.New([]registry.IOption{
+			registry.OptId("testid"),
+			registry.OptBind("127.0.0.1:7370"),
+			registry.OptBindAdvertise("127.0.0.1:7370"),
+			registry.OptRegistries(""),
+			registry.OptAddr("127.0.0.1:9000"),
+			registry.OptAdvertise("127.0.0.1:9000"),
+		})
+		go r.Serve()
+		time.Sleep(sleepTime)
+		return r
+	}
+
+	r := newRegistry()
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+
+	// The membership of the group is saved when it is loaded.
+	c, err := client.NewRpcClient("127.0.0.1:9000", client.OptSnapshotFile(file))
+	assert.Nil(t, err)
+	expected := make(map[string]*registry.Service)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		expected[key], err = c.Match(serviceGroup, key)
+		assert.Nil(t, err)
+	}
+	c.Close()
+	r.Close()
+	_, err = os.Stat(file)
+	assert.Nil(t, err)
+
+	// The registry is unreachable at startup, the ring is restored from the snapshot file and marked stale.
+	c, err = client.NewRpcClient("127.0.0.1:9000", client.OptSnapshotFile(file))
+	assert.Nil(t, err)
+	for key, service := range expected {
+		matched, err := c.Match(serviceGroup, key)
+		assert.Nil(t, err)
+		assert.Equal(t, service, matched)
+	}
+	staleness, ok := c.Staleness(serviceGroup)
+	assert.True(t, ok)
+	assert.Greater(t, staleness, time.Duration(0))
+
+	_, err = c.Match("othergroup", "werben")
+	assert.NotNil(t, err)
+
+	// The group is up to date again once the registry is back.
+	r = newRegistry()
+	assert.Nil(t, r.OnMemberJoin(member1))
+	time.Sleep(time.Second * 2)
+	staleness, ok = c.Staleness(serviceGroup)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), staleness)
+	service, err := c.Match(serviceGroup, "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, &member1.Service, service)
+
+	c.Close()
+	r.Close()
+}
+
+func Test_RpcClientSnapshotEjected(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	serviceGroup := "testgroup"
+	service1 := &registry.Service{Id: "testid1", Group: serviceGroup, Addr: "127.0.0.1:80", Ejected: true}
+	service2 := &registry.Service{Id: "testid2", Group: serviceGroup, Addr: "127.0.0.1:81"}
+	ring := registry.NewRing(10000)
+	ring.Upsert(service1.Id, nil)
+	ring.Upsert(service2.Id, nil)
+
+	// The ejections in a snapshot file are not restored, the key werben stays on its owner testid1.
+	data, err := json.Marshal(map[string]any{
+		"groups": map[string]any{
+			serviceGroup: map[string]any{
+				"replicas": 10000,
+				"services": []*registry.Service{service1, service2},
+				"saved":    time.Now(),
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(file, data, 0600))
+
+	c, err := client.NewRpcClient("127.0.0.1:9000", client.OptSnapshotFile(file))
+	assert.Nil(t, err)
+	for _, key := range []string{"werben", "1testid2"} {
+		expected, _, err := ring.Match(key)
+		assert.Nil(t, err)
+		service, err := c.Match(serviceGroup, key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, service.Id)
+		assert.False(t, service.Ejected)
+	}
+	c.Close()
+}
+
+func Test_RpcClientSnapshotHang(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	serviceGroup := "testgroup"
+	service := &registry.Service{Id: "testid1", Group: serviceGroup, Addr: "127.0.0.1:80"}
+	data, err := json.Marshal(map[string]any{
+		"groups": map[string]any{
+			serviceGroup: map[string]any{
+				"replicas": 10000,
+				"services": []*registry.Service{service},
+				"saved":    time.Now(),
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(file, data, 0600))
+
+	// The registry accepts the connections but never answers, the group is restored once the call times out.
+	stop := hang(t, "127.0.0.1:9000")
+	c, err := client.NewRpcClient("127.0.0.1:9000", client.OptSnapshotFile(file), client.OptTimeout(300*time.Millisecond))
+	assert.Nil(t, err)
+	matched, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, service, matched)
+	c.Close()
+	stop()
+}
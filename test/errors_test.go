@@ -5,10 +5,15 @@
 package test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/werbenhu/chash"
 	"github.com/werbenhu/registry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestErrString(t *testing.T) {
@@ -28,3 +33,39 @@ func TestErrErrorr(t *testing.T) {
 
 	require.Equal(t, "error", error(c).Error())
 }
+
+func TestErrGRPCStatus(t *testing.T) {
+	st := registry.ErrGroupNotFound.GRPCStatus()
+	require.Equal(t, codes.NotFound, st.Code())
+	require.Equal(t, registry.ErrGroupNotFound.Msg, st.Message())
+	require.Len(t, st.Details(), 1)
+
+	detail := st.Details()[0].(*registry.ErrorDetail)
+	require.Equal(t, int32(registry.ErrGroupNotFound.Code), detail.Code)
+
+	require.Equal(t, codes.Unknown, registry.Err{Code: 1, Msg: "unknown"}.GRPCStatus().Code())
+}
+
+func TestToStatusError(t *testing.T) {
+	require.Nil(t, registry.ToStatusError(nil))
+	require.Equal(t, codes.NotFound, status.Code(registry.ToStatusError(chash.ErrGroupNotFound)))
+	require.Equal(t, codes.FailedPrecondition, status.Code(registry.ToStatusError(chash.ErrNoResultMatched)))
+	require.Equal(t, codes.InvalidArgument, status.Code(registry.ToStatusError(registry.ErrMatchCount)))
+	require.Equal(t, codes.Unauthenticated, status.Code(registry.ToStatusError(registry.ErrTokenInvalid)))
+	require.Equal(t, codes.Canceled, status.Code(registry.ToStatusError(context.Canceled)))
+	require.Equal(t, codes.Internal, status.Code(registry.ToStatusError(errors.New("internal"))))
+
+	err := status.Error(codes.Unavailable, "unavailable")
+	require.Equal(t, err, registry.ToStatusError(err))
+}
+
+func TestFromStatusError(t *testing.T) {
+	err := registry.FromStatusError(registry.ToStatusError(chash.ErrGroupNotFound))
+	require.True(t, errors.Is(err, registry.ErrGroupNotFound))
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	err = status.Error(codes.Unavailable, "unavailable")
+	require.Equal(t, err, registry.FromStatusError(err))
+
+	require.Nil(t, registry.FromStatusError(nil))
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_RpcClientFailover(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member))
+
+	_, err := client.NewRpcClient(" , ")
+	assert.Equal(t, registry.ErrRegistryAddrEmpty, err)
+
+	// The first registry server is down, the calls fail over to the second one.
+	c, err := client.NewRpcClient("127.0.0.1:9001,127.0.0.1:9000", client.OptProbeInterval(sleepTime))
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		service, err := c.Match(serviceGroup, "werben")
+		assert.Nil(t, err)
+		assert.Equal(t, &member.Service, service)
+
+		services, err := c.Members(serviceGroup)
+		assert.Nil(t, err)
+		assert.Equal(t, []*registry.Service{&member.Service}, services)
+	}
+
+	// The second server is down and the first one comes back.
+	r.Close()
+	r = registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7371"),
+		registry.OptBindAdvertise("127.0.0.1:7371"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9001"),
+		registry.OptAdvertise("127.0.0.1:9001"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime * 3)
+	assert.Nil(t, r.OnMemberJoin(member))
+
+	service, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+
+	// All the servers are down.
+	r.Close()
+	_, err = c.Match(serviceGroup, "werben")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	c.Close()
+}
+
+func Test_RpcClientDiscover(t *testing.T) {
+	r1 := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r1.Serve()
+	time.Sleep(sleepTime)
+	r2 := registry.New([]registry.IOption{
+		registry.OptId("testid2"),
+		registry.OptBind("127.0.0.1:7371"),
+		registry.OptBindAdvertise("127.0.0.1:7371"),
+		registry.OptRegistries("127.0.0.1:7370"),
+		registry.OptAddr("127.0.0.1:9001"),
+		registry.OptAdvertise("127.0.0.1:9001"),
+	})
+	go r2.Serve()
+	time.Sleep(sleepTime * 3)
+
+	// All the registry servers are found from a single seed.
+	c, err := client.NewRpcClient("127.0.0.1:9000", client.OptDiscover(true), client.OptProbeInterval(sleepTime))
+	assert.Nil(t, err)
+	registries, err := c.Registries()
+	assert.Nil(t, err)
+	assert.Len(t, registries, 2)
+	assert.Equal(t, "testid", registries[0].Id)
+	assert.Equal(t, "127.0.0.1:9000", registries[0].Addr)
+	assert.Equal(t, "testid2", registries[1].Id)
+	assert.Equal(t, "127.0.0.1:9001", registries[1].Addr)
+
+	// The seed is down, the calls fail over to the discovered server.
+	r1.Close()
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7371", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r2.OnMemberJoin(member))
+
+	service, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+
+	c.Close()
+	r2.Close()
+}
+
+func Test_RpcClientFailoverHang(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member))
+
+	// The first registry server accepts the connections but never answers.
+	stop := hang(t, "127.0.0.1:9001")
+
+	// The call fails over to the second server before its timeout runs out.
+	c, err := client.NewRpcClient("127.0.0.1:9001,127.0.0.1:9000", client.OptTimeout(time.Second))
+	assert.Nil(t, err)
+	start := time.Now()
+	service, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+	assert.Less(t, time.Since(start), time.Second)
+	c.Close()
+
+	stop()
+	r.Close()
+}
+
+// hang listens on addr and accepts the connections but never answers, like a registry server that hangs.
+// The returned function stops listening and closes the connections.
+func hang(t *testing.T, addr string) func() {
+	listener, err := net.Listen("tcp", addr)
+	assert.Nil(t, err)
+	conns := make([]net.Conn, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	return func() {
+		listener.Close()
+		<-done
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+}
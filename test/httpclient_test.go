@@ -0,0 +1,218 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+)
+
+func Test_HttpClient(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	h := registry.NewHttp()
+	go h.Start("127.0.0.1:9002")
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	member1.SetTag("zone", "cn-east")
+	assert.Nil(t, r.OnMemberJoin(member1))
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+
+	rc, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	// The first server is down, the requests move to the second one.
+	var c client.Interface
+	c, err = client.NewHttpClient("127.0.0.1:9003,127.0.0.1:9002")
+	assert.Nil(t, err)
+
+	// The http client returns the same results as the gRPC client.
+	for _, key := range []string{"werben", "1testid2", "key1", "key2"} {
+		expected, err := rc.Match(serviceGroup, key)
+		assert.Nil(t, err)
+		service, err := c.Match(serviceGroup, key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, service)
+
+		expectedN, err := rc.MatchN(serviceGroup, key, 2)
+		assert.Nil(t, err)
+		services, err := c.MatchN(serviceGroup, key, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedN, services)
+	}
+
+	keys := []string{"werben", "1testid2", "key1", "key2", "werben"}
+	expectedMatches, err := rc.MatchMany(serviceGroup, keys)
+	assert.Nil(t, err)
+	matches, err := c.MatchMany(serviceGroup, keys)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedMatches, matches)
+
+	expectedMembers, err := rc.Members(serviceGroup)
+	assert.Nil(t, err)
+	members, err := c.Members(serviceGroup)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, expectedMembers, members)
+
+	groups, err := c.ListGroups()
+	assert.Nil(t, err)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, serviceGroup, groups[1].Name)
+	assert.Equal(t, 2, groups[1].Members)
+
+	registries, err := c.(*client.HttpClient).Registries()
+	assert.Nil(t, err)
+	assert.Len(t, registries, 1)
+	assert.Equal(t, "127.0.0.1:9000", registries[0].Addr)
+
+	ejected, err := c.ReportFailure(serviceGroup, "testid1")
+	assert.Nil(t, err)
+	assert.False(t, ejected)
+
+	// The error codes are converted to the registry errors.
+	_, err = c.ReportFailure(serviceGroup, "notexist")
+	assert.True(t, errors.Is(err, registry.ErrServiceNotFound))
+	_, err = c.Match("othergroup", "werben")
+	assert.True(t, errors.Is(err, registry.ErrGroupNotFound))
+	_, err = c.MatchN(serviceGroup, "werben", 0)
+	assert.True(t, errors.Is(err, registry.ErrMatchCount))
+	_, err = c.Members("othergroup")
+	assert.True(t, errors.Is(err, registry.ErrGroupNotFound))
+
+	c.Close()
+	rc.Close()
+	h.Stop()
+	r.Close()
+}
+
+func Test_HttpErrorStatus(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHttp("127.0.0.1:9002", ""),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	// A group whose services have all left is empty.
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", "emptygroup", "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member))
+	assert.Nil(t, r.OnMemberLeave(member))
+
+	// The errors are answered with their http status, and the code and message of the registry error.
+	cases := []struct {
+		method string
+		path   string
+		status int
+		err    registry.Err
+	}{
+		{http.MethodGet, "/match?group=othergroup&key=werben", http.StatusNotFound, registry.ErrGroupNotFound},
+		{http.MethodGet, "/match?group=emptygroup&key=werben", http.StatusServiceUnavailable, registry.ErrGroupEmpty},
+		{http.MethodGet, "/matchn?group=emptygroup&key=werben&n=x", http.StatusBadRequest, registry.ErrMatchCount},
+		{http.MethodGet, "/matchmany?group=othergroup&key=werben", http.StatusNotFound, registry.ErrGroupNotFound},
+		{http.MethodGet, "/members?group=othergroup", http.StatusNotFound, registry.ErrGroupNotFound},
+		{http.MethodPost, "/report?group=emptygroup&id=notexist", http.StatusNotFound, registry.ErrServiceNotFound},
+		{http.MethodPut, "/services/notexist/heartbeat", http.StatusNotFound, registry.ErrServiceNotFound},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, "http://127.0.0.1:9002"+c.path, nil)
+		assert.Nil(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+
+		body := &struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}{}
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(body))
+		resp.Body.Close()
+		assert.Equal(t, c.status, resp.StatusCode, c.path)
+		assert.Equal(t, c.err.Code, body.Code, c.path)
+		assert.Equal(t, c.err.Msg, body.Msg, c.path)
+	}
+
+	// The http client returns the registry errors.
+	hc, err := client.NewHttpClient("127.0.0.1:9002")
+	assert.Nil(t, err)
+	_, err = hc.Match("emptygroup", "werben")
+	assert.True(t, errors.Is(err, registry.ErrGroupEmpty))
+
+	hc.Close()
+	r.Close()
+}
+
+func Test_HttpClientWatch(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHttp("127.0.0.1:9002", ""),
+	})
+	go r.Serve()
+
+	// The first server fails every request with a server error.
+	broken := &http.Server{
+		Addr: "127.0.0.1:9003",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}),
+	}
+	go broken.ListenAndServe()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+
+	// The requests move to the second server.
+	c, err := client.NewHttpClient("127.0.0.1:9003,127.0.0.1:9002")
+	assert.Nil(t, err)
+	service, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member1.Service, service)
+
+	w, err := c.Watch(serviceGroup)
+	assert.Nil(t, err)
+	e := <-w.Events()
+	assert.Equal(t, registry.EventSnapshot, e.Type)
+	assert.Equal(t, []*registry.Service{&member1.Service}, e.Services)
+
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+	e = <-w.Events()
+	assert.Equal(t, registry.EventJoin, e.Type)
+	assert.Equal(t, serviceGroup, e.Group)
+	assert.Equal(t, []*registry.Service{&member2.Service}, e.Services)
+
+	// The events channel is closed once the watcher is stopped.
+	w.Stop()
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+	assert.Nil(t, w.Err())
+
+	c.Close()
+	broken.Close()
+	r.Close()
+}
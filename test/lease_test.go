@@ -0,0 +1,253 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+)
+
+// leaseResponse is the response of the registration endpoints of the http api.
+type leaseResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Service registry.Service `json:"service"`
+		TTL     int              `json:"ttl"`
+	} `json:"data"`
+}
+
+func callLease(t *testing.T, method string, url string, body any) *leaseResponse {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		assert.Nil(t, err)
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	assert.Nil(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	ret := &leaseResponse{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(ret))
+	return ret
+}
+
+func Test_HttpRegister(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHttp("127.0.0.1:9002", ""),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	url := "http://127.0.0.1:9002/services"
+
+	// The services are registered with their tags as the meta.
+	ret := callLease(t, http.MethodPut, url, map[string]any{
+		"id":    "php1",
+		"group": serviceGroup,
+		"addr":  "127.0.0.1:80",
+		"tags":  map[string]string{"version": "v1"},
+		"ttl":   1,
+	})
+	assert.Equal(t, 0, ret.Code)
+	assert.Equal(t, 1, ret.Data.TTL)
+	assert.Equal(t, "v1", ret.Data.Service.Meta["version"])
+	assert.Equal(t, []*registry.Service{&ret.Data.Service}, r.Members(serviceGroup))
+
+	// A random id is generated if it is not set.
+	ret = callLease(t, http.MethodPut, url, map[string]any{"group": serviceGroup, "addr": "127.0.0.1:81"})
+	assert.Equal(t, 0, ret.Code)
+	assert.Equal(t, int(registry.DefaultLeaseTTL/time.Second), ret.Data.TTL)
+	assert.NotEmpty(t, ret.Data.Service.Id)
+	assert.Len(t, r.Members(serviceGroup), 2)
+
+	ret = callLease(t, http.MethodDelete, url+"/"+ret.Data.Service.Id, nil)
+	assert.Equal(t, 0, ret.Code)
+	assert.Len(t, r.Members(serviceGroup), 1)
+
+	// The service stays registered while its heartbeats are received.
+	for i := 0; i < 4; i++ {
+		time.Sleep(sleepTime * 4)
+		ret = callLease(t, http.MethodPut, url+"/php1/heartbeat", nil)
+		assert.Equal(t, 0, ret.Code)
+		assert.Equal(t, "php1", ret.Data.Service.Id)
+	}
+	assert.Len(t, r.Members(serviceGroup), 1)
+
+	// The service expires once the heartbeats stop.
+	time.Sleep(sleepTime * 12)
+	assert.Len(t, r.Members(serviceGroup), 0)
+	ret = callLease(t, http.MethodPut, url+"/php1/heartbeat", nil)
+	assert.Equal(t, registry.ErrServiceNotFound.Code, ret.Code)
+	ret = callLease(t, http.MethodDelete, url+"/php1", nil)
+	assert.Equal(t, registry.ErrServiceNotFound.Code, ret.Code)
+
+	// The invalid registrations are rejected.
+	ret = callLease(t, http.MethodPut, url, map[string]any{"group": serviceGroup})
+	assert.Equal(t, registry.ErrServiceAddrEmpty.Code, ret.Code)
+	ret = callLease(t, http.MethodPut, url, map[string]any{"addr": "127.0.0.1:80"})
+	assert.Equal(t, registry.ErrGroupNameEmpty.Code, ret.Code)
+	ret = callLease(t, http.MethodPut, url, "invalid")
+	assert.Equal(t, registry.ErrRequestBody.Code, ret.Code)
+	for _, tag := range []string{registry.TagGroup, registry.TagAddr, registry.TagReplicas, registry.TagHttp} {
+		ret = callLease(t, http.MethodPut, url, map[string]any{
+			"group": serviceGroup,
+			"addr":  "127.0.0.1:80",
+			"tags":  map[string]string{tag: "100000000"},
+		})
+		assert.Equal(t, registry.ErrRequestBody.Code, ret.Code, tag)
+	}
+
+	// A service registered over serf can't be taken over.
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member))
+	ret = callLease(t, http.MethodPut, url, map[string]any{"id": "testid1", "group": serviceGroup, "addr": "127.0.0.1:82"})
+	assert.Equal(t, registry.ErrServiceConflict.Code, ret.Code)
+
+	r.Close()
+}
+
+func Test_HttpRegisterReplicated(t *testing.T) {
+	r1 := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHttp("127.0.0.1:9002", ""),
+	})
+	go r1.Serve()
+	time.Sleep(sleepTime)
+
+	r2 := registry.New([]registry.IOption{
+		registry.OptId("testid2"),
+		registry.OptBind("127.0.0.1:7371"),
+		registry.OptBindAdvertise("127.0.0.1:7371"),
+		registry.OptRegistries("127.0.0.1:7370"),
+		registry.OptAddr("127.0.0.1:9001"),
+		registry.OptAdvertise("127.0.0.1:9001"),
+		registry.OptHttp("127.0.0.1:9003", ""),
+	})
+	go r2.Serve()
+	time.Sleep(sleepTime * 3)
+
+	// The registration on the first registry server is received by the watchers of the second one.
+	serviceGroup := "testgroup"
+	stream := openSSE(t, "http://127.0.0.1:9003/watch?group="+serviceGroup, "")
+	_, e := stream.next(t)
+	assert.Equal(t, registry.EventSnapshot, e.Type)
+
+	ret := callLease(t, http.MethodPut, "http://127.0.0.1:9002/services", map[string]any{
+		"id":    "php1",
+		"group": serviceGroup,
+		"addr":  "127.0.0.1:80",
+	})
+	assert.Equal(t, 0, ret.Code)
+	_, e = stream.next(t)
+	assert.Equal(t, registry.EventJoin, e.Type)
+	assert.Equal(t, "php1", e.Services[0].Id)
+
+	// The heartbeats and the deregistration are accepted by any registry server.
+	ret = callLease(t, http.MethodPut, "http://127.0.0.1:9003/services/php1/heartbeat", nil)
+	assert.Equal(t, 0, ret.Code)
+	ret = callLease(t, http.MethodDelete, "http://127.0.0.1:9003/services/php1", nil)
+	assert.Equal(t, 0, ret.Code)
+	_, e = stream.next(t)
+	assert.Equal(t, registry.EventLeave, e.Type)
+	assert.Equal(t, "php1", e.Services[0].Id)
+
+	// A registration renewed on the first registry server stays on the second one after its TTL,
+	// and expires on both once the heartbeats stop.
+	ret = callLease(t, http.MethodPut, "http://127.0.0.1:9002/services", map[string]any{
+		"id":    "php2",
+		"group": serviceGroup,
+		"addr":  "127.0.0.1:81",
+		"ttl":   1,
+	})
+	assert.Equal(t, 0, ret.Code)
+	_, e = stream.next(t)
+	assert.Equal(t, registry.EventJoin, e.Type)
+	for i := 0; i < 6; i++ {
+		time.Sleep(300 * time.Millisecond)
+		ret = callLease(t, http.MethodPut, "http://127.0.0.1:9002/services/php2/heartbeat", nil)
+		assert.Equal(t, 0, ret.Code)
+	}
+	c, err := client.NewHttpClient("127.0.0.1:9003")
+	assert.Nil(t, err)
+	services, err := c.Members(serviceGroup)
+	assert.Nil(t, err)
+	assert.Len(t, services, 1)
+	c.Close()
+
+	_, e = stream.next(t)
+	assert.Equal(t, registry.EventLeave, e.Type)
+	assert.Equal(t, "php2", e.Services[0].Id)
+	stream.close()
+
+	r2.Close()
+	r1.Close()
+}
+
+// leaseHandler counts the services added to their groups by the registrations.
+type leaseHandler struct {
+	joined int
+	left   int
+}
+
+func (h *leaseHandler) OnMemberJoin(*registry.Member) error   { h.joined++; return nil }
+func (h *leaseHandler) OnMemberLeave(*registry.Member) error  { h.left++; return nil }
+func (h *leaseHandler) OnMemberUpdate(*registry.Member) error { return nil }
+
+func Test_LeasesRenewNotReplicated(t *testing.T) {
+	versions := make([]uint64, 0)
+	h := &leaseHandler{}
+	l := registry.NewLeases("testid", h, func(payload []byte) error {
+		msg := &struct {
+			Version uint64 `json:"version"`
+		}{}
+		assert.Nil(t, json.Unmarshal(payload, msg))
+		versions = append(versions, msg.Version)
+		return nil
+	})
+	defer l.Close()
+
+	member := registry.NewMember("php1", "", "", "", "leasegroup", "127.0.0.1:80")
+	assert.Nil(t, l.Register(member, time.Second))
+	assert.Equal(t, 1, h.joined)
+	assert.Len(t, versions, 1)
+
+	// The renewals are not replicated until half of the TTL has passed since the registration was put.
+	for i := 0; i < 3; i++ {
+		_, _, err := l.Renew("php1")
+		assert.Nil(t, err)
+	}
+	assert.Len(t, versions, 1)
+
+	time.Sleep(600 * time.Millisecond)
+	_, _, err := l.Renew("php1")
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+
+	// The changes are ordered by the Lamport clock.
+	assert.Nil(t, l.Deregister("php1"))
+	assert.Len(t, versions, 3)
+	assert.Equal(t, []uint64{1, 2, 3}, versions)
+	assert.Equal(t, 1, h.left)
+}
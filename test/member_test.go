@@ -125,3 +125,35 @@ func Test_MemberGetTags(t *testing.T) {
 		registry.TagReplicas: "10000",
 	}, tags)
 }
+
+func Test_MemberMeta(t *testing.T) {
+	m := registry.NewMember("test_id", "127.0.0.1:7031", "127.0.0.2:7031", "127.0.0.1:7030", "test_group", "127.0.0.1:80")
+	assert.NotNil(t, m)
+	assert.Nil(t, m.Service.Meta)
+
+	m.SetTag("version", "1.0.0")
+	m.SetTag("zone", "zone-a")
+	assert.Equal(t, map[string]string{
+		"version": "1.0.0",
+		"zone":    "zone-a",
+	}, m.Service.Meta)
+
+	tags := m.GetTags()
+	assert.Equal(t, map[string]string{
+		registry.TagAddr:     "127.0.0.1:80",
+		registry.TagGroup:    "test_group",
+		registry.TagReplicas: "10000",
+		"version":            "1.0.0",
+		"zone":               "zone-a",
+	}, tags)
+
+	payload, err := m.Marshal()
+	assert.Nil(t, err)
+	latest := &registry.Member{}
+	err = latest.Unmarshal(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, m.Service, latest.Service)
+
+	assert.True(t, registry.IsReservedTag(registry.TagGroup))
+	assert.False(t, registry.IsReservedTag("version"))
+}
@@ -0,0 +1,218 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+)
+
+func Test_RpcClientReportFailure(t *testing.T) {
+	cooldown := 500 * time.Millisecond
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptEjection(2, time.Second, cooldown),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+	member3 := registry.NewMember("testid3", "127.0.0.1:8372", "127.0.0.1:8372", "127.0.0.1:7370", serviceGroup, "127.0.0.1:82")
+	assert.Nil(t, r.OnMemberJoin(member3))
+
+	remote, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+	cached, err := client.NewRpcClient("127.0.0.1:9000", client.OptCache(true))
+	assert.Nil(t, err)
+
+	owner, err := remote.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	successors, err := remote.MatchN(serviceGroup, "werben", 2)
+	assert.Nil(t, err)
+	_, err = cached.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+
+	// The service is ejected once the threshold is reached.
+	ejected, err := remote.ReportFailure(serviceGroup, owner.Id)
+	assert.Nil(t, err)
+	assert.False(t, ejected)
+	ejected, err = remote.ReportFailure(serviceGroup, owner.Id)
+	assert.Nil(t, err)
+	assert.True(t, ejected)
+	time.Sleep(sleepTime)
+
+	// The key moves to the next service on the ring, the same as the owner had left.
+	service, err := remote.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, successors[1].Id, service.Id)
+	assert.False(t, service.Ejected)
+	service, err = cached.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, successors[1].Id, service.Id)
+
+	services, err := remote.MatchN(serviceGroup, "werben", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, owner.Id, services[0].Id)
+	assert.True(t, services[0].Ejected)
+
+	members, err := remote.Members(serviceGroup)
+	assert.Nil(t, err)
+	for _, member := range members {
+		assert.Equal(t, member.Id == owner.Id, member.Ejected)
+	}
+
+	// No more than half of the services are ejected at the same time.
+	for i := 0; i < 2; i++ {
+		ejected, err = remote.ReportFailure(serviceGroup, successors[1].Id)
+		assert.Nil(t, err)
+		assert.False(t, ejected)
+	}
+
+	_, err = remote.ReportFailure(serviceGroup, "notexist")
+	assert.True(t, errors.Is(err, registry.ErrServiceNotFound))
+	_, err = remote.ReportFailure("othergroup", owner.Id)
+	assert.True(t, errors.Is(err, registry.ErrGroupNotFound))
+
+	// The service is restored when the cooldown ends.
+	time.Sleep(cooldown + sleepTime)
+	service, err = remote.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, owner, service)
+	service, err = cached.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, owner, service)
+
+	cached.Close()
+	remote.Close()
+	r.Close()
+}
+
+func Test_RpcClientReportFailureDisabled(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptEjection(0, time.Second, time.Second),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+
+	for i := 0; i < 10; i++ {
+		ejected, err := r.ReportFailure(serviceGroup, "testid1")
+		assert.Nil(t, err)
+		assert.False(t, ejected)
+	}
+	service, err := r.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.False(t, service.Ejected)
+
+	r.Close()
+}
+
+func Test_OutliersReplicated(t *testing.T) {
+	r1 := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptEjection(1, time.Second, 2*time.Second),
+	})
+	go r1.Serve()
+	time.Sleep(sleepTime)
+
+	r2 := registry.New([]registry.IOption{
+		registry.OptId("testid2"),
+		registry.OptBind("127.0.0.1:7371"),
+		registry.OptBindAdvertise("127.0.0.1:7371"),
+		registry.OptRegistries("127.0.0.1:7370"),
+		registry.OptAddr("127.0.0.1:9001"),
+		registry.OptAdvertise("127.0.0.1:9001"),
+		registry.OptEjection(1, time.Second, 2*time.Second),
+	})
+	go r2.Serve()
+	time.Sleep(sleepTime * 3)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	for _, r := range []*registry.Registry{r1, r2} {
+		assert.Nil(t, r.OnMemberJoin(member1))
+		assert.Nil(t, r.OnMemberJoin(member2))
+	}
+
+	owner, err := r1.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	ejected, err := r1.ReportFailure(serviceGroup, owner.Id)
+	assert.Nil(t, err)
+	assert.True(t, ejected)
+	time.Sleep(sleepTime * 3)
+
+	// The other registry server assigns the key to the same service.
+	expected, err := r1.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.NotEqual(t, owner.Id, expected.Id)
+	service, err := r2.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Id, service.Id)
+	for _, member := range r2.Members(serviceGroup) {
+		assert.Equal(t, member.Id == owner.Id, member.Ejected)
+	}
+
+	r2.Close()
+	r1.Close()
+}
+
+func Test_RpcClientReportFailureOnce(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptEjection(1, time.Second, time.Second),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+
+	// A report that times out on the first registry server is not sent again to the second one.
+	stop := hang(t, "127.0.0.1:9001")
+	c, err := client.NewRpcClient("127.0.0.1:9001,127.0.0.1:9000", client.OptTimeout(500*time.Millisecond))
+	assert.Nil(t, err)
+	_, err = c.ReportFailure(serviceGroup, "testid1")
+	assert.NotNil(t, err)
+	for _, member := range r.Members(serviceGroup) {
+		assert.False(t, member.Ejected)
+	}
+	c.Close()
+
+	stop()
+	r.Close()
+}
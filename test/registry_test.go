@@ -272,3 +272,105 @@ func Test_RegistryMembers(t *testing.T) {
 	}, services)
 	r.Close()
 }
+
+func Test_RegistryMatchN(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember(
+		"testid1",
+		"127.0.0.1:8370",
+		"127.0.0.1:8370",
+		"127.0.0.1:7370",
+		serviceGroup,
+		"127.0.0.1:80",
+	)
+	err := r.OnMemberJoin(member1)
+	assert.Nil(t, err)
+
+	member2 := registry.NewMember(
+		"testid2",
+		"127.0.0.1:8371",
+		"127.0.0.1:8371",
+		"127.0.0.1:7370",
+		serviceGroup,
+		"127.0.0.1:81",
+	)
+	err = r.OnMemberJoin(member2)
+	assert.Nil(t, err)
+
+	services, err := r.MatchN(serviceGroup, "werben", 2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []*registry.Service{
+		&member1.Service, &member2.Service,
+	}, services)
+
+	services, err = r.MatchN(serviceGroup, "werben", 3)
+	assert.Nil(t, err)
+	assert.Len(t, services, 2)
+
+	_, err = r.MatchN(serviceGroup, "werben", 0)
+	assert.Equal(t, registry.ErrMatchCount, err)
+
+	_, err = r.MatchN("notexist", "werben", 1)
+	assert.Equal(t, chash.ErrGroupNotFound, err)
+
+	// The next service takes over the key when the first one left.
+	err = r.OnMemberLeave(member1)
+	assert.Nil(t, err)
+	service, err := r.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member2.Service, service)
+	r.Close()
+}
+
+func Test_RegistryListGroups(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	member := registry.NewMember(
+		"testid1",
+		"127.0.0.1:8370",
+		"127.0.0.1:8370",
+		"127.0.0.1:7370",
+		"testgroup",
+		"127.0.0.1:80",
+	)
+	err := r.OnMemberJoin(member)
+	assert.Nil(t, err)
+
+	groups := r.ListGroups()
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "testgroup", groups[1].Name)
+	assert.Equal(t, 1, groups[1].Members)
+	updated := groups[1].Updated
+
+	// An empty group is still listed after its last service left.
+	time.Sleep(time.Millisecond)
+	err = r.OnMemberLeave(member)
+	assert.Nil(t, err)
+	groups = r.ListGroups()
+	assert.Len(t, groups, 2)
+	assert.Equal(t, 0, groups[1].Members)
+	assert.True(t, groups[1].Updated.After(updated))
+	r.Close()
+
+	assert.Len(t, r.ListGroups(), 0)
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+	"github.com/werbenhu/registry/registrytest"
+)
+
+func Test_FakeSameAsRegistry(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	fake := registrytest.New()
+	serviceGroup := "testgroup"
+	for i := 1; i <= 3; i++ {
+		member := registry.NewMember(fmt.Sprintf("testid%d", i), fmt.Sprintf("127.0.0.1:%d", 8370+i),
+			fmt.Sprintf("127.0.0.1:%d", 8370+i), "127.0.0.1:7370", serviceGroup, fmt.Sprintf("127.0.0.1:%d", 80+i))
+		assert.Nil(t, r.OnMemberJoin(member))
+		fake.AddService(&member.Service)
+	}
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	// The keys are assigned to the same services as the registry.
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		expected, err := c.MatchN(serviceGroup, key, 3)
+		assert.Nil(t, err)
+		services, err := fake.MatchN(serviceGroup, key, 3)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, services)
+	}
+
+	c.Close()
+	r.Close()
+}
+
+func Test_Fake(t *testing.T) {
+	fake := registrytest.New()
+	var c client.Interface = fake
+
+	_, err := c.Match("testgroup", "werben")
+	assert.True(t, errors.Is(err, registry.ErrGroupNotFound))
+
+	fake.SetReplicas("testgroup", 100)
+	service1 := registry.NewService("testid1", "testgroup", "127.0.0.1:80")
+	service2 := registry.NewService("testid2", "testgroup", "127.0.0.1:81")
+	fake.AddService(service1)
+	fake.AddService(service2)
+
+	matches, err := c.MatchMany("testgroup", []string{"key1", "key2", "key1"})
+	assert.Nil(t, err)
+	assert.Len(t, matches.Owners, 2)
+
+	members, err := c.Members("testgroup")
+	assert.Nil(t, err)
+	assert.Equal(t, []*registry.Service{service1, service2}, members)
+
+	groups, err := c.ListGroups()
+	assert.Nil(t, err)
+	assert.Equal(t, 100, groups[0].Replicas)
+	assert.Equal(t, 2, groups[0].Members)
+
+	// The scripted errors.
+	unavailable := errors.New("unavailable")
+	fake.FailNext(registrytest.MethodMatch, unavailable)
+	_, err = c.Match("testgroup", "werben")
+	assert.Equal(t, unavailable, err)
+	_, err = c.Match("testgroup", "werben")
+	assert.Nil(t, err)
+
+	fake.SetError(registrytest.MethodMembers, unavailable)
+	_, err = c.Members("testgroup")
+	assert.Equal(t, unavailable, err)
+	fake.SetError(registrytest.MethodMembers, nil)
+	_, err = c.Members("testgroup")
+	assert.Nil(t, err)
+
+	// The keys of an ejected service move to the next service.
+	owner, err := c.Match("testgroup", "werben")
+	assert.Nil(t, err)
+	fake.SetEjected("testgroup", owner.Id, true)
+	ejected, err := c.ReportFailure("testgroup", owner.Id)
+	assert.Nil(t, err)
+	assert.True(t, ejected)
+	service, err := c.Match("testgroup", "werben")
+	assert.Nil(t, err)
+	assert.NotEqual(t, owner.Id, service.Id)
+	fake.SetEjected("testgroup", owner.Id, false)
+
+	// The keys move to the remaining service.
+	fake.RemoveService("testgroup", "testid1")
+	service, err = c.Match("testgroup", "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, service2, service)
+
+	fake.RemoveService("testgroup", "testid2")
+	_, err = c.Match("testgroup", "werben")
+	assert.True(t, errors.Is(err, registry.ErrGroupEmpty))
+
+	// The calls are recorded.
+	assert.Len(t, fake.CallsOf(registrytest.MethodMatch), 7)
+	assert.Equal(t, registrytest.Call{Method: registrytest.MethodMatchMany, Group: "testgroup", Keys: []string{"key1", "key2", "key1"}},
+		fake.CallsOf(registrytest.MethodMatchMany)[0])
+	assert.Len(t, fake.Calls(), 13)
+
+	fake.Reset()
+	assert.Len(t, fake.Calls(), 0)
+}
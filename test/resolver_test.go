@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// startBackend starts a gRPC server whose health status of the "who" service identifies it.
+func startBackend(t *testing.T, addr string, status grpc_health_v1.HealthCheckResponse_ServingStatus) *grpc.Server {
+	listener, err := net.Listen("tcp", addr)
+	assert.Nil(t, err)
+
+	hs := health.NewServer()
+	hs.SetServingStatus("who", status)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, hs)
+	go server.Serve(listener)
+	return server
+}
+
+func Test_ResolverBalancer(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	backend1 := startBackend(t, "127.0.0.1:9101", grpc_health_v1.HealthCheckResponse_SERVING)
+	backend2 := startBackend(t, "127.0.0.1:9102", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	statuses := map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+		"127.0.0.1:9101": grpc_health_v1.HealthCheckResponse_SERVING,
+		"127.0.0.1:9102": grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+	}
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:9101")
+	assert.Nil(t, r.OnMemberJoin(member1))
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:9102")
+	assert.Nil(t, r.OnMemberJoin(member2))
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	conn, err := grpc.Dial(client.Scheme+":///"+serviceGroup,
+		grpc.WithResolvers(client.NewResolverBuilder(c)),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	hc := grpc_health_v1.NewHealthClient(conn)
+
+	// Every key is routed to the same service as Match returns.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, key := range []string{"werben", "1testid2", "key1", "key2", "key3", "key4"} {
+		service, err := c.Match(serviceGroup, key)
+		assert.Nil(t, err)
+
+		resp, err := hc.Check(client.WithKey(ctx, key), &grpc_health_v1.HealthCheckRequest{Service: "who"}, grpc.WaitForReady(true))
+		assert.Nil(t, err)
+		assert.Equal(t, statuses[service.Addr], resp.Status)
+
+		// The key can be carried in the metadata as well.
+		mdCtx := metadata.AppendToOutgoingContext(ctx, client.KeyHeader, key)
+		resp, err = hc.Check(mdCtx, &grpc_health_v1.HealthCheckRequest{Service: "who"}, grpc.WaitForReady(true))
+		assert.Nil(t, err)
+		assert.Equal(t, statuses[service.Addr], resp.Status)
+	}
+
+	// A RPC without key is rejected.
+	_, err = hc.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "who"})
+	assert.True(t, errors.Is(registry.FromStatusError(err), registry.ErrNoRoutingKey))
+
+	// The keys move to the remaining service when a service leaves.
+	assert.Nil(t, r.OnMemberLeave(member2))
+	time.Sleep(sleepTime)
+	for _, key := range []string{"werben", "1testid2", "key1", "key2"} {
+		resp, err := hc.Check(client.WithKey(ctx, key), &grpc_health_v1.HealthCheckRequest{Service: "who"})
+		assert.Nil(t, err)
+		assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+	}
+
+	conn.Close()
+	c.Close()
+	backend1.Stop()
+	backend2.Stop()
+	r.Close()
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/chash"
+	"github.com/werbenhu/registry"
+)
+
+func Test_RingMatchSameAsChash(t *testing.T) {
+	group := chash.NewGroup("testgroup", 1000)
+	ring := registry.NewRing(1000)
+
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("testid%d", i)
+		group.Upsert(key, []byte(key))
+		ring.Upsert(key, []byte(key))
+	}
+	group.Delete("testid3")
+	ring.Delete("testid3")
+	assert.Equal(t, 4, ring.Len())
+	assert.Equal(t, 1000, ring.Replicas())
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-id-%d", i)
+		expected, payload, err := group.Match(key)
+		assert.Nil(t, err)
+
+		actual, actualPayload, err := ring.Match(key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, payload, actualPayload)
+	}
+}
+
+func Test_RingMatchN(t *testing.T) {
+	ring := registry.NewRing(1000)
+	_, err := ring.MatchN("werben", 1)
+	assert.Equal(t, chash.ErrNoResultMatched, err)
+
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("testid%d", i)
+		ring.Upsert(key, []byte(key))
+	}
+
+	_, err = ring.MatchN("werben", 0)
+	assert.Equal(t, registry.ErrMatchCount, err)
+
+	elements, err := ring.MatchN("werben", 10)
+	assert.Nil(t, err)
+	assert.Len(t, elements, 4)
+
+	// A huge n is not allocated for.
+	elements, err = ring.MatchN("werben", 1<<40)
+	assert.Nil(t, err)
+	assert.Len(t, elements, 4)
+	assert.Equal(t, 4, cap(elements))
+
+	// Each successor owns the key once the previous ones left.
+	for i := 0; i < 4; i++ {
+		key, _, err := ring.Match("werben")
+		assert.Nil(t, err)
+		assert.Equal(t, elements[i].Key, key)
+
+		successors, err := ring.MatchN("werben", 4)
+		assert.Nil(t, err)
+		assert.Equal(t, elements[i:], successors)
+		ring.Delete(key)
+	}
+}
+
+func Test_RingUpsertMany(t *testing.T) {
+	ring := registry.NewRing(1000)
+	batch := registry.NewRing(1000)
+
+	elements := make([]*chash.Element, 0)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("testid%d", i)
+		ring.Upsert(key, []byte(key))
+		elements = append(elements, &chash.Element{Key: key, Payload: []byte(key)})
+	}
+	batch.UpsertMany(elements...)
+	assert.Equal(t, 5, batch.Len())
+
+	// The existing keys and the keys repeated in a batch are replaced, the same as one by one.
+	ring.Upsert("testid1", []byte("updated1"))
+	ring.Upsert("testid3", []byte("updated3"))
+	batch.UpsertMany(
+		&chash.Element{Key: "testid1", Payload: []byte("stale")},
+		&chash.Element{Key: "testid3", Payload: []byte("updated3")},
+		&chash.Element{Key: "testid1", Payload: []byte("updated1")},
+	)
+	assert.Equal(t, 5, batch.Len())
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user-id-%d", i)
+		expected, err := ring.MatchN(key, 5)
+		assert.Nil(t, err)
+		actual, err := batch.MatchN(key, 5)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func Test_RingWalk(t *testing.T) {
+	ring := registry.NewRing(1000)
+	err := ring.Walk("werben", func(*chash.Element) bool { return true })
+	assert.Equal(t, chash.ErrNoResultMatched, err)
+
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("testid%d", i)
+		ring.Upsert(key, []byte(key))
+	}
+	elements, err := ring.MatchN("werben", 4)
+	assert.Nil(t, err)
+
+	// The walk visits the elements in the order of MatchN, and stops once fn returns false.
+	visited := make([]*chash.Element, 0)
+	err = ring.Walk("werben", func(element *chash.Element) bool {
+		visited = append(visited, element)
+		return len(visited) < 2
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, elements[:2], visited)
+
+	visited = visited[:0]
+	err = ring.Walk("werben", func(element *chash.Element) bool {
+		visited = append(visited, element)
+		return true
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, elements, visited)
+}
+
+func Test_RingOwner(t *testing.T) {
+	ring := registry.NewRing(1000)
+	_, err := ring.Owner("werben", func(string) bool { return false })
+	assert.Equal(t, chash.ErrNoResultMatched, err)
+
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprintf("testid%d", i)
+		ring.Upsert(key, []byte(key))
+	}
+	elements, err := ring.MatchN("werben", 4)
+	assert.Nil(t, err)
+
+	// The skipped elements are passed over in the order of MatchN.
+	owner, err := ring.Owner("werben", func(string) bool { return false })
+	assert.Nil(t, err)
+	assert.Equal(t, elements[0], owner)
+	owner, err = ring.Owner("werben", func(id string) bool {
+		return id == elements[0].Key || id == elements[1].Key
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, elements[2], owner)
+
+	// The owner is kept if all the elements are skipped.
+	owner, err = ring.Owner("werben", func(string) bool { return true })
+	assert.Nil(t, err)
+	assert.Equal(t, elements[0], owner)
+}
@@ -0,0 +1,286 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+func Test_RpcClientMatchMany(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	err := r.OnMemberJoin(member1)
+	assert.Nil(t, err)
+
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	err = r.OnMemberJoin(member2)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	matches, err := c.MatchMany(serviceGroup, []string{"werben", "1testid2", "werben"})
+	assert.Nil(t, err)
+	assert.Len(t, matches.Owners, 2)
+	assert.Equal(t, "testid1", matches.Owners["werben"].Id)
+	assert.Equal(t, "testid2", matches.Owners["1testid2"].Id)
+
+	sort.Slice(matches.Shards, func(i int, j int) bool {
+		return matches.Shards[i].Service.Id < matches.Shards[j].Service.Id
+	})
+	assert.Len(t, matches.Shards, 2)
+	assert.Equal(t, &member1.Service, matches.Shards[0].Service)
+	assert.Equal(t, []string{"werben"}, matches.Shards[0].Keys)
+	assert.Equal(t, &member2.Service, matches.Shards[1].Service)
+	assert.Equal(t, []string{"1testid2"}, matches.Shards[1].Keys)
+
+	// Every key is assigned to the same service as Match does.
+	for key, service := range matches.Owners {
+		matched, err := c.Match(serviceGroup, key)
+		assert.Nil(t, err)
+		assert.Equal(t, matched, service)
+	}
+
+	_, err = c.MatchMany("notexist", []string{"werben"})
+	assert.NotNil(t, err)
+
+	c.Close()
+	r.Close()
+}
+
+func Test_RpcClientMatchN(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	err := r.OnMemberJoin(member1)
+	assert.Nil(t, err)
+
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	err = r.OnMemberJoin(member2)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	services, err := c.MatchN(serviceGroup, "1testid2", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []*registry.Service{&member2.Service, &member1.Service}, services)
+
+	_, err = c.MatchN(serviceGroup, "1testid2", 0)
+	assert.NotNil(t, err)
+
+	c.Close()
+	r.Close()
+}
+
+func Test_RpcClientMeta(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewSimpleMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370")
+	member.SetTags(map[string]string{
+		registry.TagGroup:    serviceGroup,
+		registry.TagAddr:     "127.0.0.1:80",
+		registry.TagReplicas: registry.DefaultReplicas,
+		"version":            "1.0.0",
+	})
+	err := r.OnMemberJoin(member)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	service, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"version": "1.0.0"}, service.Meta)
+
+	services, err := c.Members(serviceGroup)
+	assert.Nil(t, err)
+	assert.Len(t, services, 1)
+	assert.Equal(t, &member.Service, services[0])
+
+	c.Close()
+	r.Close()
+}
+
+func Test_RpcClientListGroups(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	start := time.Now()
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", "testgroup1", "127.0.0.1:80")
+	err := r.OnMemberJoin(member1)
+	assert.Nil(t, err)
+
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", "testgroup2", "127.0.0.1:81")
+	member2.Replicas = "100"
+	err = r.OnMemberJoin(member2)
+	assert.Nil(t, err)
+
+	member3 := registry.NewMember("testid3", "127.0.0.1:8372", "127.0.0.1:8372", "127.0.0.1:7370", "testgroup2", "127.0.0.1:82")
+	err = r.OnMemberJoin(member3)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	groups, err := c.ListGroups()
+	assert.Nil(t, err)
+	assert.Len(t, groups, 3)
+
+	// The registry itself is a member of the registry group.
+	assert.Equal(t, "registry-group", groups[0].Name)
+	assert.Equal(t, 1, groups[0].Members)
+
+	assert.Equal(t, "testgroup1", groups[1].Name)
+	assert.Equal(t, 1, groups[1].Members)
+	assert.Equal(t, 10000, groups[1].Replicas)
+
+	// The group keeps the replicas of the first service.
+	assert.Equal(t, "testgroup2", groups[2].Name)
+	assert.Equal(t, 2, groups[2].Members)
+	assert.Equal(t, 100, groups[2].Replicas)
+	assert.False(t, groups[2].Updated.Before(start.Truncate(time.Millisecond)))
+
+	c.Close()
+	r.Close()
+}
+
+func Test_RpcClientErrors(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	err := r.OnMemberJoin(member)
+	assert.Nil(t, err)
+	err = r.OnMemberLeave(member)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	_, err = c.Match("notexist", "werben")
+	assert.True(t, errors.Is(err, registry.ErrGroupNotFound))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = c.Match(serviceGroup, "werben")
+	assert.True(t, errors.Is(err, registry.ErrGroupEmpty))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = c.Members("notexist")
+	assert.True(t, errors.Is(err, registry.ErrGroupNotFound))
+
+	_, err = c.MatchN(serviceGroup, "werben", 0)
+	assert.True(t, errors.Is(err, registry.ErrMatchCount))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	c.Close()
+	r.Close()
+}
+
+func Test_RpcClientContext(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member))
+
+	methods := make([]string, 0)
+	interceptor := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		methods = append(methods, method)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	var c client.Interface
+	c, err := client.New("127.0.0.1:9000",
+		client.OptTimeout(time.Second),
+		client.OptUnaryInterceptors(interceptor),
+		client.OptUserAgent("registry-test"),
+		client.OptKeepalive(keepalive.ClientParameters{Time: 10 * time.Second}),
+	)
+	assert.Nil(t, err)
+
+	service, err := c.MatchContext(context.Background(), serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+
+	services, err := c.MembersContext(context.Background(), serviceGroup)
+	assert.Nil(t, err)
+	assert.Equal(t, []*registry.Service{&member.Service}, services)
+	assert.Equal(t, []string{"/R/Match", "/R/Members"}, methods)
+
+	// The cancellation of the caller is passed to the call.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.MatchContext(ctx, serviceGroup, "werben")
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	c.Close()
+	r.Close()
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+func Test_RpcServerHealth(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	conn, err := grpc.Dial("127.0.0.1:9000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	health := healthpb.NewHealthClient(conn)
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{Service: "R"})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	assert.Nil(t, err)
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	assert.Nil(t, err)
+	info, err := stream.Recv()
+	assert.Nil(t, err)
+
+	services := make([]string, 0)
+	for _, service := range info.GetListServicesResponse().Service {
+		services = append(services, service.Name)
+	}
+	assert.Contains(t, services, "R")
+	assert.Contains(t, services, "grpc.health.v1.Health")
+
+	// The registry is not serving while the local member is out of the serf cluster.
+	local := registry.NewMember("testid", "127.0.0.1:7370", "127.0.0.1:7370", "", registry.RegistryGroup, "127.0.0.1:9000")
+	assert.Nil(t, r.OnMemberLeave(local))
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	assert.Nil(t, r.OnMemberJoin(local))
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	conn.Close()
+	r.Close()
+}
+
+func Test_RpcServerHealthDisabled(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHealth(false),
+		registry.OptReflection(false),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	conn, err := grpc.Dial("127.0.0.1:9000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NotNil(t, err)
+
+	conn.Close()
+	r.Close()
+}
+
+func Test_RpcServerSetServing(t *testing.T) {
+	s := registry.NewRpcServer()
+	go s.Start("127.0.0.1:9001")
+	time.Sleep(sleepTime)
+
+	conn, err := grpc.Dial("127.0.0.1:9001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The standalone server is serving from the start.
+	health := healthpb.NewHealthClient(conn)
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	s.SetServing(false)
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	s.SetServing(true)
+	resp, err = health.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	conn.Close()
+	s.Stop()
+}
+
+func Test_RpcServerRegister(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptRegisterGrace(sleepTime * 6),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	conn, err := grpc.Dial("127.0.0.1:9000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	c := registry.NewRClient(conn)
+	serviceGroup := "testgroup"
+
+	// The service is registered by the first message, a random id is generated.
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.Register(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&registry.RegisterRequest{Service: &registry.MatchResponse{
+		Group: serviceGroup,
+		Addr:  "127.0.0.1:80",
+		Meta:  map[string]string{"version": "v1"},
+	}}))
+	resp, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, resp.Service.Id)
+	assert.Equal(t, (sleepTime * 6).Milliseconds(), resp.Grace)
+	id := resp.Service.Id
+	assert.Equal(t, []*registry.Service{{Id: id, Group: serviceGroup, Addr: "127.0.0.1:80", Meta: map[string]string{"version": "v1"}}},
+		r.Members(serviceGroup))
+
+	// The later messages update the tags of the service, the reserved tags are ignored.
+	assert.Nil(t, stream.Send(&registry.RegisterRequest{Service: &registry.MatchResponse{
+		Meta: map[string]string{
+			"version":            "v2",
+			registry.TagGroup:    "othergroup",
+			registry.TagReplicas: "100000000",
+			registry.TagHttp:     "127.0.0.1:9002",
+		},
+	}}))
+	resp, err = stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, id, resp.Service.Id)
+	assert.Equal(t, []*registry.Service{{Id: id, Group: serviceGroup, Addr: "127.0.0.1:80", Meta: map[string]string{"version": "v2"}}},
+		r.Members(serviceGroup))
+
+	// The service stays registered while the stream is alive.
+	time.Sleep(sleepTime * 10)
+	assert.Len(t, r.Members(serviceGroup), 1)
+
+	// The service is removed after the grace period once the stream breaks.
+	cancel()
+	time.Sleep(sleepTime * 3)
+	assert.Len(t, r.Members(serviceGroup), 1)
+	time.Sleep(sleepTime * 6)
+	assert.Len(t, r.Members(serviceGroup), 0)
+
+	// The service is removed at once if the client closes the stream.
+	stream, err = c.Register(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&registry.RegisterRequest{Service: &registry.MatchResponse{
+		Id:    "testid1",
+		Group: serviceGroup,
+		Addr:  "127.0.0.1:81",
+	}}))
+	resp, err = stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, "testid1", resp.Service.Id)
+	assert.Len(t, r.Members(serviceGroup), 1)
+	assert.Nil(t, stream.CloseSend())
+	_, err = stream.Recv()
+	assert.NotNil(t, err)
+	assert.Len(t, r.Members(serviceGroup), 0)
+
+	// The invalid services are rejected.
+	stream, err = c.Register(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&registry.RegisterRequest{Service: &registry.MatchResponse{Group: serviceGroup}}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, registry.ErrServiceAddrEmpty, registry.FromStatusError(err))
+
+	conn.Close()
+	r.Close()
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+)
+
+// sseStream reads the events of a SSE watch stream.
+type sseStream struct {
+	resp       *http.Response
+	reader     *bufio.Reader
+	heartbeats int
+}
+
+func openSSE(t *testing.T, url string, lastId string) *sseStream {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.Nil(t, err)
+	if len(lastId) > 0 {
+		req.Header.Set("Last-Event-ID", lastId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return &sseStream{resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+// next returns the id and the event of the next event, the heartbeats are counted and skipped.
+func (s *sseStream) next(t *testing.T) (string, *registry.Event) {
+	id := ""
+	e := &registry.Event{}
+	for {
+		line, err := s.reader.ReadString('\n')
+		assert.Nil(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == ": heartbeat":
+			s.heartbeats++
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), e))
+		case line == "" && len(id) > 0:
+			return id, e
+		}
+	}
+}
+
+func (s *sseStream) close() {
+	s.resp.Body.Close()
+}
+
+func Test_HttpWatch(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptHttp("127.0.0.1:9002", ""),
+		registry.OptSSEHeartbeat(sleepTime),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	url := "http://127.0.0.1:9002/watch?group=" + serviceGroup
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+
+	// The first event is a snapshot of the group.
+	stream := openSSE(t, url, "")
+	_, e := stream.next(t)
+	assert.Equal(t, registry.EventSnapshot, e.Type)
+	assert.Equal(t, []*registry.Service{&member1.Service}, e.Services)
+	assert.Equal(t, 10000, e.Replicas)
+
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+	lastId, e := stream.next(t)
+	assert.Equal(t, registry.EventJoin, e.Type)
+	assert.Equal(t, []*registry.Service{&member2.Service}, e.Services)
+
+	// The heartbeats keep the stream alive.
+	time.Sleep(sleepTime * 3)
+	member2.SetTag("zone", "cn-east")
+	assert.Nil(t, r.OnMemberUpdate(member2))
+	lastId, e = stream.next(t)
+	assert.Equal(t, registry.EventUpdate, e.Type)
+	assert.Greater(t, stream.heartbeats, 0)
+	stream.close()
+
+	// The missed events are sent on reconnecting with the last event id.
+	assert.Nil(t, r.OnMemberLeave(member1))
+	member3 := registry.NewMember("testid3", "127.0.0.1:8372", "127.0.0.1:8372", "127.0.0.1:7370", "othergroup", "127.0.0.1:82")
+	assert.Nil(t, r.OnMemberJoin(member3))
+	stream = openSSE(t, url, lastId)
+	_, e = stream.next(t)
+	assert.Equal(t, registry.EventLeave, e.Type)
+	assert.Equal(t, "testid1", e.Services[0].Id)
+	stream.close()
+
+	// An unknown id is answered with a snapshot.
+	stream = openSSE(t, url, "unknown-1")
+	_, e = stream.next(t)
+	assert.Equal(t, registry.EventSnapshot, e.Type)
+	assert.Equal(t, []*registry.Service{&member2.Service}, e.Services)
+	stream.close()
+
+	r.Close()
+}
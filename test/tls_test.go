@@ -0,0 +1,193 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+)
+
+// testCA is a self-signed CA that issues certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "registry-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue writes a certificate signed by the CA and its key to the files.
+func (ca *testCA) issue(t *testing.T, serial int64, certFile string, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "registry-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func Test_RpcClientMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	serverCert, serverKey := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+	ca.issue(t, 2, serverCert, serverKey)
+	clientCert, clientKey := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	ca.issue(t, 3, clientCert, clientKey)
+
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+		registry.OptTLS(serverCert, serverKey),
+		registry.OptClientCA(caFile),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	err := r.OnMemberJoin(member)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000",
+		client.OptTLS(caFile, ""),
+		client.OptClientCert(clientCert, clientKey),
+	)
+	assert.Nil(t, err)
+	service, err := c.Match(serviceGroup, "werben")
+	assert.Nil(t, err)
+	assert.Equal(t, &member.Service, service)
+	c.Close()
+
+	// The connections with client certificates negotiate h2 as gRPC requires.
+	clientCfg, err := registry.NewClientTLSConfig(caFile, clientCert, clientKey, "127.0.0.1")
+	assert.Nil(t, err)
+	clientCfg.NextProtos = []string{"h2"}
+	conn, err := tls.Dial("tcp", "127.0.0.1:9000", clientCfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "h2", conn.ConnectionState().NegotiatedProtocol)
+	conn.Close()
+
+	// A client without certificate is rejected.
+	c, err = client.NewRpcClient("127.0.0.1:9000", client.OptTLS(caFile, ""))
+	assert.Nil(t, err)
+	_, err = c.Match(serviceGroup, "werben")
+	assert.NotNil(t, err)
+	c.Close()
+
+	// A client without TLS is rejected.
+	c, err = client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+	_, err = c.Match(serviceGroup, "werben")
+	assert.NotNil(t, err)
+	c.Close()
+
+	r.Close()
+}
+
+func Test_ServerTLSConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	serverCert, serverKey := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+	ca.issue(t, 2, serverCert, serverKey)
+
+	cfg, err := registry.NewServerTLSConfig(serverCert, serverKey, "")
+	assert.Nil(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:9001", cfg)
+	assert.Nil(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	clientCfg, err := registry.NewClientTLSConfig(caFile, "", "", "127.0.0.1")
+	assert.Nil(t, err)
+
+	serial := func() int64 {
+		conn, err := tls.Dial("tcp", "127.0.0.1:9001", clientCfg)
+		assert.Nil(t, err)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(2), serial())
+
+	// The rotated certificate is used by the new connections without restarting.
+	ca.issue(t, 4, serverCert, serverKey)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(serverCert, future, future)
+	assert.Equal(t, int64(4), serial())
+
+	// A server signed by another CA is rejected.
+	other := newTestCA(t)
+	other.issue(t, 5, serverCert, serverKey)
+	future = future.Add(time.Minute)
+	os.Chtimes(serverCert, future, future)
+	_, err = tls.Dial("tcp", "127.0.0.1:9001", clientCfg)
+	assert.NotNil(t, err)
+
+	listener.Close()
+}
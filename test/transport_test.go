@@ -0,0 +1,135 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+	"github.com/werbenhu/registry/registrytest"
+)
+
+func Test_Transport(t *testing.T) {
+	// Each backend answers its service id and the body of the request.
+	backend := func(id string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s %s %s", id, r.URL.Path, body)
+		}))
+	}
+	backend1 := backend("testid1")
+	defer backend1.Close()
+	backend2 := backend("testid2")
+	defer backend2.Close()
+
+	serviceGroup := "testgroup"
+	fake := registrytest.New()
+	fake.SetReplicas(serviceGroup, 100)
+	fake.AddService(registry.NewService("testid1", serviceGroup, strings.TrimPrefix(backend1.URL, "http://")))
+	fake.AddService(registry.NewService("testid2", serviceGroup, strings.TrimPrefix(backend2.URL, "http://")))
+	fake.AddService(registry.NewService("testid3", serviceGroup, "127.0.0.1:9103"))
+
+	get := func(c *http.Client, url string, header string) (string, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nil(t, err)
+		if len(header) > 0 {
+			req.Header.Set("X-User-Id", header)
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	// The requests are routed to the owners of their keys.
+	byHeader := &http.Client{Transport: client.NewTransport(fake, serviceGroup, client.KeyFromHeader("X-User-Id"))}
+	byQuery := &http.Client{Transport: client.NewTransport(fake, serviceGroup, client.KeyFromQuery("user"))}
+	byPath := &http.Client{Transport: client.NewTransport(fake, serviceGroup, client.KeyFromPath(1))}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		owners, err := fake.MatchN(serviceGroup, key, 2)
+		assert.Nil(t, err)
+		owner := owners[0]
+		if owner.Id == "testid3" {
+			// The dead service can't be connected, the request is sent to the next service on the ring.
+			owner = owners[1]
+		}
+
+		body, err := get(byHeader, "http://testgroup/users", key)
+		assert.Nil(t, err)
+		assert.Equal(t, owner.Id+" /users ", body)
+		body, err = get(byQuery, "http://testgroup/users?user="+key, "")
+		assert.Nil(t, err)
+		assert.Equal(t, owner.Id+" /users ", body)
+		body, err = get(byPath, "http://testgroup/users/"+key, "")
+		assert.Nil(t, err)
+		assert.Equal(t, owner.Id+" /users/"+key+" ", body)
+	}
+
+	// The dead service is reported, and the body is sent again to the next service.
+	assert.NotEmpty(t, fake.CallsOf(registrytest.MethodReportFailure))
+	for i := 0; ; i++ {
+		key := fmt.Sprintf("key%d", i)
+		owners, err := fake.MatchN(serviceGroup, key, 2)
+		assert.Nil(t, err)
+		if owners[0].Id != "testid3" {
+			continue
+		}
+		req, err := http.NewRequest(http.MethodPost, "http://testgroup/users", strings.NewReader("werben"))
+		assert.Nil(t, err)
+		req.Header.Set("X-User-Id", key)
+		resp, err := byHeader.Do(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, owners[1].Id+" /users werben", string(body))
+
+		// Without retries the connection error is returned.
+		once := client.NewTransport(fake, serviceGroup, client.KeyFromHeader("X-User-Id"))
+		once.Attempts = 1
+		_, err = get(&http.Client{Transport: once}, "http://testgroup/users", key)
+		assert.NotNil(t, err)
+		break
+	}
+
+	// A single attempt is sent to the service Match returns, the ejected owner is skipped.
+	fake.SetEjected(serviceGroup, "testid1", true)
+	once := client.NewTransport(fake, serviceGroup, client.KeyFromHeader("X-User-Id"))
+	once.Attempts = 1
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		owner, err := fake.Match(serviceGroup, key)
+		assert.Nil(t, err)
+		if owner.Id == "testid3" {
+			continue
+		}
+		body, err := get(&http.Client{Transport: once}, "http://testgroup/users", key)
+		assert.Nil(t, err)
+		assert.Equal(t, owner.Id+" /users ", body)
+		assert.Equal(t, "testid2 /users ", body)
+	}
+	fake.SetEjected(serviceGroup, "testid1", false)
+
+	// The key is set on the context if there is no KeyFunc.
+	byContext := &http.Client{Transport: client.NewTransport(fake, serviceGroup, nil)}
+	req, err := http.NewRequestWithContext(client.WithKey(context.Background(), "werben"), http.MethodGet, "http://testgroup/", nil)
+	assert.Nil(t, err)
+	resp, err := byContext.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+
+	_, err = get(byHeader, "http://testgroup/users", "")
+	assert.True(t, errors.Is(err, registry.ErrNoRoutingKey))
+	_, err = get(byPath, "http://testgroup/", "")
+	assert.True(t, errors.Is(err, registry.ErrNoRoutingKey))
+}
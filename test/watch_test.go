@@ -0,0 +1,177 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/werbenhu/registry"
+	"github.com/werbenhu/registry/client"
+)
+
+func Test_WatchersPublish(t *testing.T) {
+	w := registry.NewWatchers()
+	ch := w.Subscribe("testgroup")
+
+	member := registry.NewMember("testid", "127.0.0.1:8370", "127.0.0.1:8370", "", "testgroup", "127.0.0.1:80")
+	err := w.OnMemberJoin(member)
+	assert.Nil(t, err)
+
+	// Events of other groups are not received.
+	other := registry.NewMember("otherid", "127.0.0.1:8371", "127.0.0.1:8371", "", "othergroup", "127.0.0.1:81")
+	w.OnMemberJoin(other)
+
+	e := <-ch
+	assert.Equal(t, registry.EventJoin, e.Type)
+	assert.Equal(t, "testgroup", e.Group)
+	assert.Equal(t, []*registry.Service{&member.Service}, e.Services)
+	assert.Len(t, ch, 0)
+
+	w.Unsubscribe("testgroup", ch)
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func Test_WatchersSlowSubscriber(t *testing.T) {
+	w := registry.NewWatchers()
+	ch := w.Subscribe("testgroup")
+
+	member := registry.NewMember("testid", "127.0.0.1:8370", "127.0.0.1:8370", "", "testgroup", "127.0.0.1:80")
+	for i := 0; i < 100; i++ {
+		w.OnMemberUpdate(member)
+	}
+
+	count := 0
+	for range ch {
+		count++
+	}
+	assert.Less(t, count, 100)
+}
+
+func Test_RpcClientWatch(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	err := r.OnMemberJoin(member1)
+	assert.Nil(t, err)
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	w, err := c.Watch(serviceGroup)
+	assert.Nil(t, err)
+
+	e := <-w.Events()
+	assert.Equal(t, registry.EventSnapshot, e.Type)
+	assert.Equal(t, serviceGroup, e.Group)
+	assert.Equal(t, []*registry.Service{&member1.Service}, e.Services)
+
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	err = r.OnMemberJoin(member2)
+	assert.Nil(t, err)
+
+	e = <-w.Events()
+	assert.Equal(t, registry.EventJoin, e.Type)
+	assert.Equal(t, []*registry.Service{&member2.Service}, e.Services)
+
+	member2.Service.Addr = "127.0.0.1:82"
+	err = r.OnMemberUpdate(member2)
+	assert.Nil(t, err)
+
+	e = <-w.Events()
+	assert.Equal(t, registry.EventUpdate, e.Type)
+	assert.Equal(t, "127.0.0.1:82", e.Services[0].Addr)
+
+	err = r.OnMemberLeave(member1)
+	assert.Nil(t, err)
+
+	e = <-w.Events()
+	assert.Equal(t, registry.EventLeave, e.Type)
+	assert.Equal(t, []*registry.Service{&member1.Service}, e.Services)
+
+	w.Stop()
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+	assert.Nil(t, w.Err())
+
+	c.Close()
+	r.Close()
+}
+
+func Test_RpcClientWatchKeys(t *testing.T) {
+	r := registry.New([]registry.IOption{
+		registry.OptId("testid"),
+		registry.OptBind("127.0.0.1:7370"),
+		registry.OptBindAdvertise("127.0.0.1:7370"),
+		registry.OptRegistries(""),
+		registry.OptAddr("127.0.0.1:9000"),
+		registry.OptAdvertise("127.0.0.1:9000"),
+	})
+	go r.Serve()
+	time.Sleep(sleepTime)
+
+	serviceGroup := "testgroup"
+	member1 := registry.NewMember("testid1", "127.0.0.1:8370", "127.0.0.1:8370", "127.0.0.1:7370", serviceGroup, "127.0.0.1:80")
+	assert.Nil(t, r.OnMemberJoin(member1))
+
+	c, err := client.NewRpcClient("127.0.0.1:9000")
+	assert.Nil(t, err)
+
+	keys := []string{"key1", "key2", "key3", "key4", "key5", "key6"}
+	w := c.WatchKeys(serviceGroup, append(keys, "key1"))
+
+	// The first events carry the initial owners.
+	for range keys {
+		e := <-w.Events()
+		assert.Nil(t, e.Old)
+		assert.Equal(t, &member1.Service, e.New)
+	}
+
+	// Only the keys that move to the new service raise events.
+	member2 := registry.NewMember("testid2", "127.0.0.1:8371", "127.0.0.1:8371", "127.0.0.1:7370", serviceGroup, "127.0.0.1:81")
+	assert.Nil(t, r.OnMemberJoin(member2))
+	time.Sleep(sleepTime)
+
+	moved := make([]string, 0)
+	for _, key := range keys {
+		service, err := c.Match(serviceGroup, key)
+		assert.Nil(t, err)
+		if service.Id == member2.Service.Id {
+			moved = append(moved, key)
+		}
+	}
+	assert.NotEmpty(t, moved)
+	for _, key := range moved {
+		e := <-w.Events()
+		assert.Equal(t, key, e.Key)
+		assert.Equal(t, serviceGroup, e.Group)
+		assert.Equal(t, &member1.Service, e.Old)
+		assert.Equal(t, &member2.Service, e.New)
+	}
+
+	// The keys move back when the service leaves.
+	assert.Nil(t, r.OnMemberLeave(member2))
+	for _, key := range moved {
+		e := <-w.Events()
+		assert.Equal(t, key, e.Key)
+		assert.Equal(t, &member2.Service, e.Old)
+		assert.Equal(t, &member1.Service, e.New)
+	}
+
+	w.Stop()
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+
+	c.Close()
+	r.Close()
+}
@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader loads a certificate and key pair, and reloads it whenever the files are modified,
+// so that certificates can be rotated without restarting.
+type CertReloader struct {
+	sync.Mutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time
+}
+
+// NewCertReloader creates a CertReloader and loads the certificate and key pair.
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := r.Certificate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Certificate returns the certificate, it is reloaded if the files are modified since the last load.
+// If the reloading fails, for example the files are being written, the previous certificate is returned.
+func (r *CertReloader) Certificate() (*tls.Certificate, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil && r.cert == nil {
+		return nil, err
+	}
+	if r.cert != nil && (err != nil || !modTime.After(r.modTime)) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert == nil {
+			return nil, err
+		}
+		log.Printf("[ERROR] reload certificate %s err:%s\n", r.certFile, err.Error())
+		return r.cert, nil
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	return r.cert, nil
+}
+
+// GetCertificate can be used as the tls.Config.GetCertificate of a server.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate()
+}
+
+// GetClientCertificate can be used as the tls.Config.GetClientCertificate of a client.
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate()
+}
+
+// CAReloader loads a PEM file of CA certificates, and reloads it whenever the file is modified.
+type CAReloader struct {
+	sync.Mutex
+	file    string
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// NewCAReloader creates a CAReloader and loads the CA certificates.
+func NewCAReloader(file string) (*CAReloader, error) {
+	r := &CAReloader{file: file}
+	if _, err := r.Pool(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Pool returns the CA certificates, it is reloaded if the file is modified since the last load.
+// If the reloading fails, the previous certificates are returned.
+func (r *CAReloader) Pool() (*x509.CertPool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	modTime, err := latestModTime(r.file)
+	if err != nil && r.pool == nil {
+		return nil, err
+	}
+	if r.pool != nil && (err != nil || !modTime.After(r.modTime)) {
+		return r.pool, nil
+	}
+
+	pem, err := os.ReadFile(r.file)
+	if err == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			err = ErrParseCA
+		} else {
+			r.pool = pool
+			r.modTime = modTime
+			return r.pool, nil
+		}
+	}
+
+	if r.pool == nil {
+		return nil, err
+	}
+	log.Printf("[ERROR] reload CA %s err:%s\n", r.file, err.Error())
+	return r.pool, nil
+}
+
+// latestModTime returns the latest modification time of the files.
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// NewServerTLSConfig creates the TLS config of a server.
+// If clientCAFile is set, the clients must present a certificate signed by it (mutual TLS).
+// The certificate and the CA are reloaded when their files are modified.
+func NewServerTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	cert, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The protocols are negotiated by ALPN, gRPC requires h2.
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: cert.GetCertificate,
+	}
+	if len(clientCAFile) == 0 {
+		return cfg, nil
+	}
+
+	ca, err := NewCAReloader(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// A config is created for each connection so that a rotated CA takes effect,
+	// it is cloned from the outer config so that the connections negotiate the same protocols.
+	base := cfg.Clone()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		pool, err := ca.Pool()
+		if err != nil {
+			return nil, err
+		}
+		c := base.Clone()
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		c.ClientCAs = pool
+		return c, nil
+	}
+	return cfg, nil
+}
+
+// NewClientTLSConfig creates the TLS config of a client.
+// caFile is the CA to verify the server, the system CAs are used if it is empty.
+// certFile and keyFile are the client certificate for mutual TLS, they can be empty.
+// serverName overrides the name used to verify the server certificate, it can be empty.
+// The certificate and the CA are reloaded when their files are modified.
+func NewClientTLSConfig(caFile string, certFile string, keyFile string, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := NewCertReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = cert.GetClientCertificate
+	}
+
+	if len(caFile) == 0 {
+		return cfg, nil
+	}
+
+	ca, err := NewCAReloader(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The default verification only accepts a fixed RootCAs, so it is replaced by
+	// VerifyConnection, which verifies the server against the latest CA instead.
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		pool, err := ca.Pool()
+		if err != nil {
+			return err
+		}
+		if len(cs.PeerCertificates) == 0 {
+			return ErrNoPeerCertificate
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         pool,
+			DNSName:       cs.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err = cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: 2023 werbenhu
+// SPDX-FileContributor: werbenhu
+
+package registry
+
+import (
+	"sync"
+)
+
+const (
+	// EventSnapshot carries all the current services of a group, it is always the first event of a watch.
+	EventSnapshot = "snapshot"
+
+	// EventJoin is sent when a new service joined the group.
+	EventJoin = "join"
+
+	// EventLeave is sent when a service left the group.
+	EventLeave = "leave"
+
+	// EventUpdate is sent when a service of the group is updated.
+	EventUpdate = "update"
+
+	// watchBuffer is the number of events that can be queued for a watcher.
+	watchBuffer = 64
+)
+
+// Event represents a membership change of a group.
+type Event struct {
+	// The type of the event, one of snapshot, join, leave and update.
+	Type string `json:"type"`
+
+	// The group name of the services.
+	Group string `json:"group"`
+
+	// The services related to the event.
+	Services []*Service `json:"services"`
+
+	// The number of replicated elements of each service on the group's ring, 0 if the group doesn't exist yet.
+	Replicas int `json:"replicas"`
+
+	// seq is the sequence number of the event on the http api, it is the id to resume an SSE stream from.
+	seq uint64
+}
+
+// Watchers dispatches membership events to the subscribers of each group.
+type Watchers struct {
+	sync.RWMutex
+	subs map[string]map[chan *Event]struct{}
+}
+
+// NewWatchers creates a new Watchers object.
+func NewWatchers() *Watchers {
+	return &Watchers{
+		subs: make(map[string]map[chan *Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel that receives the events of the group.
+// The channel is closed if the subscriber can't keep up with the events,
+// the subscriber should subscribe again and reload a snapshot.
+func (w *Watchers) Subscribe(group string) chan *Event {
+	w.Lock()
+	defer w.Unlock()
+
+	ch := make(chan *Event, watchBuffer)
+	if w.subs[group] == nil {
+		w.subs[group] = make(map[chan *Event]struct{})
+	}
+	w.subs[group][ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe removes the channel from the subscribers of the group.
+func (w *Watchers) Unsubscribe(group string, ch chan *Event) {
+	w.Lock()
+	defer w.Unlock()
+	w.remove(group, ch)
+}
+
+// remove deletes and closes the channel, the caller must hold the lock.
+func (w *Watchers) remove(group string, ch chan *Event) {
+	subs, ok := w.subs[group]
+	if !ok {
+		return
+	}
+	if _, ok := subs[ch]; !ok {
+		return
+	}
+	delete(subs, ch)
+	close(ch)
+	if len(subs) == 0 {
+		delete(w.subs, group)
+	}
+}
+
+// Publish sends the event to all the subscribers of the event's group.
+func (w *Watchers) Publish(e *Event) {
+	w.Lock()
+	defer w.Unlock()
+
+	for ch := range w.subs[e.Group] {
+		select {
+		case ch <- e:
+		default:
+			// The subscriber is too slow, drop it rather than blocking the serf events.
+			w.remove(e.Group, ch)
+		}
+	}
+}
+
+// Close closes all the subscribers.
+func (w *Watchers) Close() {
+	w.Lock()
+	defer w.Unlock()
+	for group, subs := range w.subs {
+		for ch := range subs {
+			w.remove(group, ch)
+		}
+	}
+}
+
+// OnMemberJoin publishes a join event of the member.
+func (w *Watchers) OnMemberJoin(m *Member) error {
+	w.publishMember(EventJoin, m)
+	return nil
+}
+
+// OnMemberLeave publishes a leave event of the member.
+func (w *Watchers) OnMemberLeave(m *Member) error {
+	w.publishMember(EventLeave, m)
+	return nil
+}
+
+// OnMemberUpdate publishes an update event of the member.
+func (w *Watchers) OnMemberUpdate(m *Member) error {
+	w.publishMember(EventUpdate, m)
+	return nil
+}
+
+// publishMember publishes an event that carries the service of the member.
+func (w *Watchers) publishMember(typ string, m *Member) {
+	service := m.Service
+	w.Publish(&Event{
+		Type:     typ,
+		Group:    service.Group,
+		Services: []*Service{&service},
+	})
+}